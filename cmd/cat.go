@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/phlv/txtar/internal"
+	"golang.org/x/tools/txtar"
+)
+
+var catCmd = &cobra.Command{
+	Use:   "cat [ARCHIVE] [NAME...]",
+	Short: "Print an archive or selected files from it",
+	Long: `Print a txtar archive in plain form, decompressing and decrypting it if needed.
+With NAME arguments, print only the contents of those entries.
+Reads from stdin if ARCHIVE is '-' or not specified.`,
+	RunE: runCat,
+}
+
+func init() {
+	rootCmd.AddCommand(catCmd)
+}
+
+func runCat(cmd *cobra.Command, args []string) error {
+	archivePath := "-"
+	if len(args) > 0 {
+		archivePath = args[0]
+	}
+
+	archive, err := internal.ReadArchive(archivePath)
+	if err != nil {
+		return fmt.Errorf("failed to read archive: %w", err)
+	}
+
+	if len(args) < 2 {
+		_, err = os.Stdout.Write(txtar.Format(archive))
+		return err
+	}
+
+	for _, name := range args[1:] {
+		found := false
+		for _, f := range archive.Files {
+			if f.Name == name {
+				if _, err := os.Stdout.Write(f.Data); err != nil {
+					return err
+				}
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("file not found in archive: %s", name)
+		}
+	}
+
+	return nil
+}
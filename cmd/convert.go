@@ -0,0 +1,35 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/phlv/txtar/internal"
+)
+
+var convertCmd = &cobra.Command{
+	Use:   "convert IN OUT",
+	Short: "Convert between txtar, tar, tar.gz, zip and directories",
+	Long: `Convert an archive between txtar, tar, tar.gz, and zip, or to and from a directory.
+Formats are detected from file extensions; directories are detected by existing
+or by a trailing slash. Use '-' as OUT to write to stdout.`,
+	Args: cobra.ExactArgs(2),
+	RunE: runConvert,
+}
+
+var convertOpts internal.ConvertOptions
+
+func init() {
+	rootCmd.AddCommand(convertCmd)
+
+	convertCmd.Flags().StringVar(&convertOpts.From, "from", "", "Input format: txtar, tar, tar.gz, zip, or dir (default: detect)")
+	convertCmd.Flags().StringVar(&convertOpts.To, "to", "", "Output format: txtar, tar, tar.gz, zip, or dir (default: detect)")
+	convertCmd.Flags().StringVar(&convertOpts.Binary, "binary", "skip", "Binary files in txtar output: skip, encode (base64), or include")
+}
+
+func runConvert(cmd *cobra.Command, args []string) error {
+	if err := internal.Convert(args[0], args[1], convertOpts); err != nil {
+		return fmt.Errorf("convert failed: %w", err)
+	}
+	return nil
+}
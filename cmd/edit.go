@@ -0,0 +1,166 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/phlv/txtar/internal"
+	"golang.org/x/tools/txtar"
+)
+
+var addCmd = &cobra.Command{
+	Use:   "add ARCHIVE NAME",
+	Short: "Add a file to a txtar archive in place",
+	Long: `Append a new file entry to an archive, preserving its comment and file order.
+Contents are read from --from-file, or from stdin if not given.
+The archive is created if it does not exist.`,
+	Args: cobra.ExactArgs(2),
+	RunE: runAdd,
+}
+
+var rmCmd = &cobra.Command{
+	Use:   "rm ARCHIVE NAME...",
+	Short: "Remove files from a txtar archive in place",
+	Long: `Remove file entries from an archive. NAME may be an exact entry name
+or a glob pattern; each must match at least one entry.`,
+	Args: cobra.MinimumNArgs(2),
+	RunE: runRm,
+}
+
+var mvCmd = &cobra.Command{
+	Use:   "mv ARCHIVE OLD NEW",
+	Short: "Rename a file inside a txtar archive",
+	Args:  cobra.ExactArgs(3),
+	RunE:  runMv,
+}
+
+var replaceCmd = &cobra.Command{
+	Use:   "replace ARCHIVE NAME",
+	Short: "Replace the contents of a file inside a txtar archive",
+	Long: `Replace the contents of an existing entry, keeping its position.
+Contents are read from --from-file, or from stdin if not given.`,
+	Args: cobra.ExactArgs(2),
+	RunE: runReplace,
+}
+
+var (
+	addFromFile     string
+	replaceFromFile string
+)
+
+func init() {
+	rootCmd.AddCommand(addCmd)
+	rootCmd.AddCommand(rmCmd)
+	rootCmd.AddCommand(mvCmd)
+	rootCmd.AddCommand(replaceCmd)
+
+	addCmd.Flags().StringVarP(&addFromFile, "from-file", "f", "", "Read contents from file instead of stdin")
+	replaceCmd.Flags().StringVarP(&replaceFromFile, "from-file", "f", "", "Read contents from file instead of stdin")
+}
+
+func readEditArchive(path string, create bool) (*txtar.Archive, error) {
+	archive, err := internal.ReadArchive(path)
+	if errors.Is(err, os.ErrNotExist) && create {
+		return &txtar.Archive{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read archive: %w", err)
+	}
+	return archive, nil
+}
+
+func readContents(fromFile string) ([]byte, error) {
+	var data []byte
+	var err error
+
+	if fromFile == "" || fromFile == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(fromFile)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to read contents: %w", err)
+	}
+	return data, nil
+}
+
+func writeEditArchive(path string, archive *txtar.Archive) error {
+	if err := internal.WriteArchiveFile(path, archive); err != nil {
+		return fmt.Errorf("failed to write archive: %w", err)
+	}
+	return nil
+}
+
+func runAdd(cmd *cobra.Command, args []string) error {
+	archive, err := readEditArchive(args[0], true)
+	if err != nil {
+		return err
+	}
+
+	data, err := readContents(addFromFile)
+	if err != nil {
+		return err
+	}
+
+	if err := internal.AddFile(archive, args[1], data); err != nil {
+		return err
+	}
+
+	return writeEditArchive(args[0], archive)
+}
+
+func runRm(cmd *cobra.Command, args []string) error {
+	archive, err := readEditArchive(args[0], false)
+	if err != nil {
+		return err
+	}
+
+	removed, err := internal.RemoveFiles(archive, args[1:])
+	if err != nil {
+		return err
+	}
+
+	if err := writeEditArchive(args[0], archive); err != nil {
+		return err
+	}
+
+	for _, name := range removed {
+		fmt.Fprintf(os.Stderr, "Removed: %s\n", name)
+	}
+	return nil
+}
+
+func runMv(cmd *cobra.Command, args []string) error {
+	archive, err := readEditArchive(args[0], false)
+	if err != nil {
+		return err
+	}
+
+	if err := internal.RenameFile(archive, args[1], args[2]); err != nil {
+		return err
+	}
+
+	return writeEditArchive(args[0], archive)
+}
+
+func runReplace(cmd *cobra.Command, args []string) error {
+	archive, err := readEditArchive(args[0], false)
+	if err != nil {
+		return err
+	}
+
+	data, err := readContents(replaceFromFile)
+	if err != nil {
+		return err
+	}
+
+	if err := internal.ReplaceFile(archive, args[1], data); err != nil {
+		return err
+	}
+
+	return writeEditArchive(args[0], archive)
+}
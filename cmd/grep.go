@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/phlv/txtar/internal"
+)
+
+var grepCmd = &cobra.Command{
+	Use:   "grep PATTERN [ARCHIVE...]",
+	Short: "Search file contents inside txtar archives",
+	Long: `Search the entries of one or more txtar archives using an RE2 regular
+expression. Matches are printed as name:line:text.
+Reads from stdin if no ARCHIVE is given or ARCHIVE is '-'.
+Exits with status 1 when nothing matches and 2 on errors.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: runGrep,
+}
+
+var grepOpts internal.GrepOptions
+
+func init() {
+	rootCmd.AddCommand(grepCmd)
+
+	grepCmd.Flags().BoolVarP(&grepOpts.IgnoreCase, "ignore-case", "i", false, "Case-insensitive matching")
+	grepCmd.Flags().BoolVarP(&grepOpts.LineNumbers, "line-number", "n", false, "Print line numbers relative to each entry")
+	grepCmd.Flags().BoolVarP(&grepOpts.FilesWithMatches, "files-with-matches", "l", false, "Only print names of matching entries")
+	grepCmd.Flags().IntVarP(&grepOpts.Context, "context", "C", 0, "Print N lines of context around matches")
+	grepCmd.Flags().StringSliceVar(&grepOpts.Include, "include", []string{}, "Only search entries matching these patterns (glob)")
+	grepCmd.Flags().StringSliceVar(&grepOpts.Exclude, "exclude", []string{}, "Skip entries matching these patterns (glob)")
+}
+
+func runGrep(cmd *cobra.Command, args []string) error {
+	grepOpts.Pattern = args[0]
+
+	archives := args[1:]
+	if len(archives) == 0 {
+		archives = []string{"-"}
+	}
+
+	total := 0
+	for _, archivePath := range archives {
+		opts := grepOpts
+		if len(archives) > 1 {
+			opts.Label = archivePath
+		}
+
+		rc, err := internal.OpenArchive(archivePath)
+		if err != nil {
+			return exitWith(2, fmt.Errorf("failed to read archive: %w", err))
+		}
+
+		n, err := internal.Grep(rc, os.Stdout, opts)
+		rc.Close()
+		if err != nil {
+			return exitWith(2, fmt.Errorf("grep failed: %w", err))
+		}
+		total += n
+	}
+
+	if total == 0 {
+		return silentExit(cmd, 1)
+	}
+
+	return nil
+}
@@ -0,0 +1,92 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/phlv/txtar/internal"
+)
+
+var lintCmd = &cobra.Command{
+	Use:   "lint [ARCHIVE]",
+	Short: "Check a txtar archive for malformed or unsafe entries",
+	Long: `Report problems that txtar parsing silently accepts: duplicate, empty,
+unsafe, or non-portable names, case-insensitive collisions, missing trailing
+newlines, lines that look like markers, and oversized files.
+Reads from stdin if ARCHIVE is '-' or not specified.
+Exits with status 1 when errors are found (or warnings, with --strict).`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runLint,
+}
+
+var (
+	lintFormat      string
+	lintMaxFileSize string
+	lintStrict      bool
+)
+
+type lintReport struct {
+	Archive  string               `json:"archive"`
+	Errors   int                  `json:"errors"`
+	Warnings int                  `json:"warnings"`
+	Issues   []internal.LintIssue `json:"issues"`
+}
+
+func init() {
+	rootCmd.AddCommand(lintCmd)
+
+	lintCmd.Flags().StringVar(&lintFormat, "format", "text", "Output format: text or json")
+	lintCmd.Flags().StringVar(&lintMaxFileSize, "max-file-size", "1M", "Warn about files larger than this (0 to disable)")
+	lintCmd.Flags().BoolVar(&lintStrict, "strict", false, "Exit with status 1 on warnings too")
+}
+
+func runLint(cmd *cobra.Command, args []string) error {
+	archivePath := "-"
+	if len(args) > 0 {
+		archivePath = args[0]
+	}
+
+	if lintFormat != "text" && lintFormat != "json" {
+		return fmt.Errorf("unknown format %q (want text or json)", lintFormat)
+	}
+
+	maxSize, err := internal.ParseSize(lintMaxFileSize)
+	if err != nil {
+		return fmt.Errorf("invalid --max-file-size: %w", err)
+	}
+
+	data, err := internal.ReadArchiveData(archivePath)
+	if err != nil {
+		return fmt.Errorf("failed to read archive: %w", err)
+	}
+
+	issues := internal.Lint(data, internal.LintOptions{MaxFileSize: maxSize})
+	errors, warnings := internal.CountLintIssues(issues)
+
+	label := archivePath
+	if label == "-" {
+		label = "<stdin>"
+	}
+
+	if lintFormat == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(lintReport{Archive: label, Errors: errors, Warnings: warnings, Issues: issues}); err != nil {
+			return err
+		}
+	} else {
+		for _, issue := range issues {
+			fmt.Printf("%s:%d: %s: %s [%s]\n", label, issue.Line, issue.Severity, issue.Message, issue.Rule)
+		}
+		if len(issues) > 0 {
+			fmt.Fprintf(os.Stderr, "%d errors, %d warnings\n", errors, warnings)
+		}
+	}
+
+	if errors > 0 || (lintStrict && warnings > 0) {
+		return silentExit(cmd, 1)
+	}
+	return nil
+}
@@ -1,12 +1,12 @@
 package cmd
 
 import (
+	"encoding/json"
 	"fmt"
-	"io"
 	"os"
 
 	"github.com/spf13/cobra"
-	"golang.org/x/tools/txtar"
+	"github.com/phlv/txtar/internal"
 )
 
 var listCmd = &cobra.Command{
@@ -17,33 +17,86 @@ Reads from stdin if ARCHIVE is '-' or not specified.`,
 	RunE: runList,
 }
 
+var (
+	listOpts      internal.ListOptions
+	listLong      bool
+	listJSON      bool
+	listJSONL     bool
+	listTree      bool
+	listTokenizer string
+)
+
 func init() {
 	rootCmd.AddCommand(listCmd)
+
+	listCmd.Flags().BoolVarP(&listLong, "long", "l", false, "Show size, line count, type and SHA-256 for each file")
+	listCmd.Flags().BoolVar(&listJSON, "json", false, "Print entries as a JSON array")
+	listCmd.Flags().BoolVar(&listJSONL, "jsonl", false, "Print one JSON object per entry")
+	listCmd.Flags().BoolVar(&listTree, "tree", false, "Print entries as a directory tree")
+	listCmd.Flags().StringVar(&listTokenizer, "tokenizer", "bpe", "Token estimator: bpe or chars")
+	listCmd.Flags().StringVar(&listOpts.Sort, "sort", "", "Sort entries by name or size")
+	listCmd.Flags().StringSliceVarP(&listOpts.Include, "include", "i", []string{}, "Include patterns (glob)")
+	listCmd.Flags().StringSliceVarP(&listOpts.Exclude, "exclude", "e", []string{}, "Exclude patterns (glob)")
 }
 
 func runList(cmd *cobra.Command, args []string) error {
-	var data []byte
-	var err error
+	formats := 0
+	for _, set := range []bool{listLong, listJSON, listJSONL, listTree} {
+		if set {
+			formats++
+		}
+	}
+	if formats > 1 {
+		return fmt.Errorf("--long, --json, --jsonl, and --tree are mutually exclusive")
+	}
+
+	tok, err := internal.NewTokenizer(listTokenizer)
+	if err != nil {
+		return err
+	}
+	listOpts.Tokenizer = tok
 
 	archivePath := "-"
 	if len(args) > 0 {
 		archivePath = args[0]
 	}
 
-	if archivePath == "-" {
-		data, err = io.ReadAll(os.Stdin)
-	} else {
-		data, err = os.ReadFile(archivePath)
+	rc, err := internal.OpenArchive(archivePath)
+	if err != nil {
+		return fmt.Errorf("failed to read archive: %w", err)
 	}
+	defer rc.Close()
 
+	entries, err := internal.ListEntries(rc, listOpts)
 	if err != nil {
 		return fmt.Errorf("failed to read archive: %w", err)
 	}
 
-	archive := txtar.Parse(data)
-
-	for _, file := range archive.Files {
-		fmt.Println(file.Name)
+	switch {
+	case listJSON:
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if entries == nil {
+			entries = []internal.EntryInfo{}
+		}
+		return enc.Encode(entries)
+	case listJSONL:
+		enc := json.NewEncoder(os.Stdout)
+		for _, e := range entries {
+			if err := enc.Encode(e); err != nil {
+				return err
+			}
+		}
+	case listLong:
+		internal.PrintLong(os.Stdout, entries)
+		internal.PrintSummary(os.Stdout, entries)
+	case listTree:
+		internal.PrintTree(os.Stdout, entries)
+		internal.PrintSummary(os.Stdout, entries)
+	default:
+		for _, e := range entries {
+			fmt.Println(e.Name)
+		}
 	}
 
 	return nil
@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/phlv/txtar/internal"
+	"golang.org/x/tools/txtar"
+)
+
+var mergeCmd = &cobra.Command{
+	Use:   "merge ARCHIVE[=PREFIX]...",
+	Short: "Combine multiple txtar archives into one",
+	Long: `Combine several txtar archives into one, in argument order.
+Append =PREFIX to an archive to place its files under PREFIX (e.g. b.txtar=vendor/).
+Comments are concatenated. Duplicate names are resolved with --on-conflict.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: runMerge,
+}
+
+var (
+	mergeOutput string
+	mergeOpts   internal.MergeOptions
+)
+
+func init() {
+	rootCmd.AddCommand(mergeCmd)
+
+	mergeCmd.Flags().StringVarP(&mergeOutput, "output", "o", "-", "Output file path, '-' for stdout")
+	mergeCmd.Flags().StringVar(&mergeOpts.Policy, "on-conflict", "error", "Duplicate name policy: first, last, error, or rename")
+	mergeCmd.Flags().StringVar(&mergeOpts.Suffix, "rename-suffix", "~", "Suffix inserted before the extension when renaming duplicates")
+}
+
+func runMerge(cmd *cobra.Command, args []string) error {
+	var inputs []internal.MergeInput
+	for _, arg := range args {
+		source, prefix := internal.ParseMergeInput(arg)
+
+		archive, err := internal.ReadArchive(source)
+		if err != nil {
+			return fmt.Errorf("failed to read archive %q: %w", source, err)
+		}
+
+		inputs = append(inputs, internal.MergeInput{
+			Source:  source,
+			Prefix:  prefix,
+			Archive: archive,
+		})
+	}
+
+	merged, err := internal.Merge(inputs, mergeOpts)
+	if err != nil {
+		return fmt.Errorf("merge failed: %w", err)
+	}
+
+	data := txtar.Format(merged)
+
+	if mergeOutput == "-" {
+		_, err = os.Stdout.Write(data)
+	} else {
+		err = os.WriteFile(mergeOutput, data, 0644)
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to write output: %w", err)
+	}
+
+	return nil
+}
@@ -4,11 +4,12 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 	"github.com/phlv/txtar/internal"
-	"golang.org/x/tools/txtar"
 )
 
 var packCmd = &cobra.Command{
@@ -19,7 +20,19 @@ Supports filtering, Git integration, and various output options.`,
 	RunE: runPack,
 }
 
-var packOpts internal.PackOptions
+var (
+	packOpts            internal.PackOptions
+	packCommentTemplate string
+	packCommentFile     string
+	packCompress        string
+	packRecipients      []string
+	packPassphrase      bool
+	packFormat          string
+	packNoHooks         bool
+	packMaxFileSize     string
+	packMaxTotalSize    string
+	packTransforms      []string
+)
 
 func init() {
 	rootCmd.AddCommand(packCmd)
@@ -37,10 +50,52 @@ func init() {
 	packCmd.Flags().BoolVar(&packOpts.DryRun, "dry-run", false, "Show files to be packed without creating archive")
 	packCmd.Flags().BoolVar(&packOpts.IgnoreBinary, "ignore-binary", false, "Skip binary files")
 	packCmd.Flags().StringVar(&packOpts.TxtarIgnore, "txtarignore", ".txtarignore", "Path to txtarignore file")
+	packCmd.Flags().IntVar(&packOpts.MaxTokens, "max-tokens", 0, "Drop files until the archive fits in N estimated tokens")
+	packCmd.Flags().StringVar(&packOpts.Tokenizer, "tokenizer", "bpe", "Token estimator: bpe or chars")
+	packCmd.Flags().StringSliceVar(&packOpts.Priority, "priority", []string{}, "Globs of files to keep first under --max-tokens and --order custom, most important first")
+	packCmd.Flags().StringVar(&packOpts.Order, "order", "", "Order of archive entries: path, size, mtime, git-recent, or custom (by --priority); default keeps collection order")
+
+	packCmd.Flags().BoolVar(&packOpts.Header, "header", false, "Write a provenance header into the archive comment")
+	packCmd.Flags().StringVar(&packCommentTemplate, "comment-template", "", "Go text/template for the archive comment (implies --header)")
+	packCmd.Flags().StringVar(&packCommentFile, "comment-file", "", "Read the archive comment template from a file (implies --header)")
+
+	packCmd.Flags().BoolVar(&packOpts.Manifest, "manifest", false, "Embed a SHA-256 manifest of all files in the archive comment")
+
+	packCmd.Flags().StringVar(&packFormat, "format", "txtar", "Output format: txtar, markdown, json, or xml")
+	packCmd.Flags().StringVar(&packCompress, "compress", "auto", "Compression: auto (from output extension), none, gzip, or zstd")
+	packCmd.Flags().StringSliceVar(&packRecipients, "recipient", []string{}, "Encrypt to an age recipient (age1... or SSH public key)")
+	packCmd.Flags().BoolVar(&packPassphrase, "passphrase", false, "Encrypt with the passphrase in TXTAR_PASSPHRASE")
+	packCmd.Flags().StringVar(&packMaxFileSize, "max-file-size", "", "Limit each file to this size, e.g. 200k or 1M")
+	packCmd.Flags().StringVar(&packMaxTotalSize, "max-total-size", "", "Limit the total size of packed contents, e.g. 10M")
+	packCmd.Flags().IntVar(&packOpts.MaxLines, "max-lines", 0, "Limit each file to N lines")
+	packCmd.Flags().StringVar(&packOpts.LimitPolicy, "limit-policy", "skip", "What to do with files over a size or line limit: skip, truncate, or fail")
+	packCmd.Flags().StringVar(&packOpts.FileSizePolicy, "max-file-size-policy", "", "Policy for --max-file-size; defaults to --limit-policy")
+	packCmd.Flags().StringVar(&packOpts.TotalSizePolicy, "max-total-size-policy", "", "Policy for --max-total-size; defaults to --limit-policy")
+	packCmd.Flags().StringVar(&packOpts.LinesPolicy, "max-lines-policy", "", "Policy for --max-lines; defaults to --limit-policy")
+	packCmd.Flags().StringVar(&packOpts.Secrets, "secrets", "warn", "Secret scanning: off, warn, fail, or redact")
+	packCmd.Flags().BoolVar(&packOpts.Outline, "outline", false, "Pack Go declarations and signatures and the first lines of other files; --include matches keep full contents")
+	packCmd.Flags().IntVar(&packOpts.OutlineLines, "outline-lines", internal.DefaultOutlineLines, "Lines of each non-Go file to keep with --outline")
+	packCmd.Flags().StringArrayVar(&packTransforms, "transform", []string{}, "Apply transforms to matching files: [GLOB=]NAME[,NAME...] with license, comments, long-strings, blank-lines")
+	packCmd.Flags().StringSliceVar(&packOpts.GoPackages, "go-pkg", []string{}, "Pack Go packages and their in-module dependencies, e.g. ./internal/foo or ./...")
+	packCmd.Flags().BoolVar(&packOpts.WithTests, "with-tests", false, "Include _test.go files of the --go-pkg packages")
+	packCmd.Flags().BoolVar(&packOpts.ExportsOnly, "exports-only", false, "Keep only exported Go declarations and stub out function bodies")
+	packCmd.Flags().BoolVar(&packNoHooks, "no-hooks", false, "Do not run pack.pre and pack.post hooks from the config file")
 
 	viper.BindPFlag("pack.output", packCmd.Flags().Lookup("output"))
 	viper.BindPFlag("pack.exclude", packCmd.Flags().Lookup("exclude"))
 	viper.BindPFlag("pack.ignore_binary", packCmd.Flags().Lookup("ignore-binary"))
+	viper.BindPFlag("pack.tokenizer", packCmd.Flags().Lookup("tokenizer"))
+	viper.BindPFlag("pack.header", packCmd.Flags().Lookup("header"))
+	viper.BindPFlag("pack.secrets", packCmd.Flags().Lookup("secrets"))
+	viper.BindPFlag("pack.max_file_size", packCmd.Flags().Lookup("max-file-size"))
+	viper.BindPFlag("pack.max_total_size", packCmd.Flags().Lookup("max-total-size"))
+	viper.BindPFlag("pack.max_lines", packCmd.Flags().Lookup("max-lines"))
+	viper.BindPFlag("pack.limit_policy", packCmd.Flags().Lookup("limit-policy"))
+	viper.BindPFlag("pack.max_file_size_policy", packCmd.Flags().Lookup("max-file-size-policy"))
+	viper.BindPFlag("pack.max_total_size_policy", packCmd.Flags().Lookup("max-total-size-policy"))
+	viper.BindPFlag("pack.max_lines_policy", packCmd.Flags().Lookup("max-lines-policy"))
+	viper.BindPFlag("pack.order", packCmd.Flags().Lookup("order"))
+	viper.BindPFlag("pack.priority", packCmd.Flags().Lookup("priority"))
 }
 
 func runPack(cmd *cobra.Command, args []string) error {
@@ -58,6 +113,124 @@ func runPack(cmd *cobra.Command, args []string) error {
 		packOpts.IgnoreBinary = viper.GetBool("pack.ignore_binary")
 	}
 
+	if viper.IsSet("pack.tokenizer") && !cmd.Flags().Changed("tokenizer") {
+		packOpts.Tokenizer = viper.GetString("pack.tokenizer")
+	}
+
+	if viper.IsSet("pack.header") && !cmd.Flags().Changed("header") {
+		packOpts.Header = viper.GetBool("pack.header")
+	}
+
+	if viper.IsSet("pack.secrets") && !cmd.Flags().Changed("secrets") {
+		packOpts.Secrets = viper.GetString("pack.secrets")
+	}
+
+	if viper.IsSet("pack.max_file_size") && !cmd.Flags().Changed("max-file-size") {
+		packMaxFileSize = viper.GetString("pack.max_file_size")
+	}
+
+	if viper.IsSet("pack.max_total_size") && !cmd.Flags().Changed("max-total-size") {
+		packMaxTotalSize = viper.GetString("pack.max_total_size")
+	}
+
+	if viper.IsSet("pack.max_lines") && !cmd.Flags().Changed("max-lines") {
+		packOpts.MaxLines = viper.GetInt("pack.max_lines")
+	}
+
+	if viper.IsSet("pack.limit_policy") && !cmd.Flags().Changed("limit-policy") {
+		packOpts.LimitPolicy = viper.GetString("pack.limit_policy")
+	}
+
+	if viper.IsSet("pack.max_file_size_policy") && !cmd.Flags().Changed("max-file-size-policy") {
+		packOpts.FileSizePolicy = viper.GetString("pack.max_file_size_policy")
+	}
+
+	if viper.IsSet("pack.max_total_size_policy") && !cmd.Flags().Changed("max-total-size-policy") {
+		packOpts.TotalSizePolicy = viper.GetString("pack.max_total_size_policy")
+	}
+
+	if viper.IsSet("pack.max_lines_policy") && !cmd.Flags().Changed("max-lines-policy") {
+		packOpts.LinesPolicy = viper.GetString("pack.max_lines_policy")
+	}
+
+	if packMaxFileSize != "" {
+		size, err := internal.ParseSize(packMaxFileSize)
+		if err != nil {
+			return fmt.Errorf("invalid --max-file-size: %w", err)
+		}
+		packOpts.MaxFileSize = size
+	}
+
+	if packMaxTotalSize != "" {
+		size, err := internal.ParseSize(packMaxTotalSize)
+		if err != nil {
+			return fmt.Errorf("invalid --max-total-size: %w", err)
+		}
+		packOpts.MaxTotalSize = size
+	}
+
+	if viper.IsSet("pack.order") && !cmd.Flags().Changed("order") {
+		packOpts.Order = viper.GetString("pack.order")
+	}
+
+	if viper.IsSet("pack.priority") && !cmd.Flags().Changed("priority") {
+		packOpts.Priority = viper.GetStringSlice("pack.priority")
+	}
+
+	if viper.IsSet("pack.transforms") && !cmd.Flags().Changed("transform") {
+		packTransforms = viper.GetStringSlice("pack.transforms")
+	}
+
+	transforms, err := internal.ParseTransforms(packTransforms)
+	if err != nil {
+		return err
+	}
+	packOpts.Transforms = transforms
+
+	if viper.IsSet("pack.secret_rules") {
+		var rules []struct {
+			Name    string
+			Pattern string
+		}
+		if err := viper.UnmarshalKey("pack.secret_rules", &rules); err != nil {
+			return fmt.Errorf("invalid pack.secret_rules: %w", err)
+		}
+		for _, r := range rules {
+			rule, err := internal.CompileSecretRule(r.Name, r.Pattern)
+			if err != nil {
+				return err
+			}
+			packOpts.SecretRules = append(packOpts.SecretRules, rule)
+		}
+	}
+
+	if packCommentTemplate != "" && packCommentFile != "" {
+		return fmt.Errorf("--comment-template and --comment-file are mutually exclusive")
+	}
+
+	packOpts.CommentTemplate = packCommentTemplate
+	if packCommentFile != "" {
+		text, err := os.ReadFile(packCommentFile)
+		if err != nil {
+			return fmt.Errorf("failed to read comment file: %w", err)
+		}
+		packOpts.CommentTemplate = string(text)
+	}
+
+	packOpts.Version = rootCmd.Version
+
+	if !packNoHooks {
+		packOpts.PreHooks = hookCommands("pack.pre")
+	}
+
+	if len(packOpts.GoPackages) > 0 && packOpts.Git {
+		return fmt.Errorf("--go-pkg cannot be combined with --git")
+	}
+
+	if packOpts.WithTests && len(packOpts.GoPackages) == 0 {
+		return fmt.Errorf("--with-tests requires --go-pkg")
+	}
+
 	if (packOpts.Diff || packOpts.Commit != "" || packOpts.Since > 0 || packOpts.Staged || packOpts.Worktree) && !packOpts.Git {
 		return fmt.Errorf("Git-specific flags require --git")
 	}
@@ -95,7 +268,37 @@ func runPack(cmd *cobra.Command, args []string) error {
 		return nil
 	}
 
-	data := txtar.Format(archive)
+	compression := packCompress
+	if compression == "auto" {
+		compression = internal.CompressionForPath(packOpts.Output)
+	}
+
+	passphrase := ""
+	if packPassphrase {
+		passphrase = os.Getenv("TXTAR_PASSPHRASE")
+		if passphrase == "" {
+			return fmt.Errorf("--passphrase requires TXTAR_PASSPHRASE to be set")
+		}
+	}
+
+	recipients, err := internal.ParseRecipients(packRecipients, passphrase)
+	if err != nil {
+		return err
+	}
+
+	if strings.HasSuffix(packOpts.Output, ".age") && len(recipients) == 0 {
+		return fmt.Errorf("output ends in .age; use --recipient or --passphrase to encrypt")
+	}
+
+	formatted, err := internal.FormatArchiveAs(archive, packFormat)
+	if err != nil {
+		return err
+	}
+
+	data, err := internal.EncodeArchive(formatted, compression, recipients)
+	if err != nil {
+		return fmt.Errorf("failed to encode output: %w", err)
+	}
 
 	if packOpts.Output == "-" {
 		_, err = os.Stdout.Write(data)
@@ -107,5 +310,19 @@ func runPack(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to write output: %w", err)
 	}
 
+	if !packNoHooks {
+		if hooks := hookCommands("pack.post"); len(hooks) > 0 {
+			output := packOpts.Output
+			if output != "-" {
+				if abs, err := filepath.Abs(output); err == nil {
+					output = abs
+				}
+			}
+			if err := internal.RunHooks("pack.post", hooks, packOpts.Dir, files, "TXTAR_ARCHIVE="+output); err != nil {
+				return err
+			}
+		}
+	}
+
 	return nil
 }
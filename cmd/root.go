@@ -1,14 +1,20 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
+	"github.com/phlv/txtar/internal"
 )
 
-var cfgFile string
+var (
+	cfgFile      string
+	identityFile string
+)
 
 var rootCmd = &cobra.Command{
 	Use:   "txtar",
@@ -23,10 +29,51 @@ func Execute() error {
 	return rootCmd.Execute()
 }
 
+func SetVersion(version string) {
+	rootCmd.Version = version
+}
+
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e *exitError) Error() string {
+	if e.err != nil {
+		return e.err.Error()
+	}
+	return fmt.Sprintf("exit status %d", e.code)
+}
+
+func (e *exitError) Unwrap() error {
+	return e.err
+}
+
+// exitWith reports err as usual but makes the command exit with code.
+func exitWith(code int, err error) error {
+	return &exitError{code: code, err: err}
+}
+
+// silentExit makes the command exit with code without printing an error or usage.
+func silentExit(cmd *cobra.Command, code int) error {
+	cmd.SilenceErrors = true
+	cmd.SilenceUsage = true
+	return &exitError{code: code}
+}
+
+func ExitCode(err error) int {
+	var exitErr *exitError
+	if errors.As(err, &exitErr) {
+		return exitErr.code
+	}
+	return 1
+}
+
 func init() {
-	cobra.OnInitialize(initConfig)
+	cobra.OnInitialize(initConfig, initIdentities)
 
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.config/txtar/config.yaml)")
+	rootCmd.PersistentFlags().StringVar(&identityFile, "identity", "", "age identity file for reading encrypted archives")
 }
 
 func initConfig() {
@@ -51,3 +98,50 @@ func initConfig() {
 		fmt.Fprintln(os.Stderr, "Using config file:", viper.ConfigFileUsed())
 	}
 }
+
+func initIdentities() {
+	ids, err := internal.LoadIdentities(identityFile, os.Getenv("TXTAR_PASSPHRASE"))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	internal.SetIdentities(ids)
+}
+
+// trustedConfig reports whether the config file in use may define hooks or
+// replace the unpack deny-list: one named by --config or the one in
+// ~/.config/txtar. A config.yaml picked up from the working directory may
+// come from an untrusted checkout.
+func trustedConfig() bool {
+	if cfgFile != "" {
+		return true
+	}
+
+	used := viper.ConfigFileUsed()
+	home, err := os.UserHomeDir()
+	if used == "" || err != nil {
+		return false
+	}
+
+	dir, err := filepath.Abs(filepath.Dir(used))
+	return err == nil && dir == filepath.Join(home, ".config", "txtar")
+}
+
+// hookCommands reads a hook list from the config file. A single string is
+// accepted as one command. Hooks in an untrusted config file are ignored.
+func hookCommands(key string) []string {
+	if viper.IsSet(key) && !trustedConfig() {
+		fmt.Fprintf(os.Stderr, "Ignoring %s hooks from %s; hooks are only read from ~/.config/txtar or --config\n", key, viper.ConfigFileUsed())
+		return nil
+	}
+
+	switch v := viper.Get(key).(type) {
+	case nil:
+		return nil
+	case string:
+		return []string{v}
+	default:
+		return viper.GetStringSlice(key)
+	}
+}
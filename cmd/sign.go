@@ -0,0 +1,78 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	"github.com/phlv/txtar/internal"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/tools/txtar"
+)
+
+var signCmd = &cobra.Command{
+	Use:   "sign ARCHIVE",
+	Short: "Sign a txtar archive",
+	Long: `Sign a txtar archive with an ed25519 or SSH private key. The signature
+covers the comment and every entry and is stored in the archive comment.
+The archive is rewritten in place unless --output is given.
+Defaults to ~/.ssh/id_ed25519 when --key is not set.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runSign,
+}
+
+var (
+	signKey    string
+	signOutput string
+)
+
+func init() {
+	rootCmd.AddCommand(signCmd)
+
+	signCmd.Flags().StringVarP(&signKey, "key", "k", "", "Private key file (PKCS#8 PEM or OpenSSH)")
+	signCmd.Flags().StringVarP(&signOutput, "output", "o", "", "Write the signed archive here instead of in place, '-' for stdout")
+}
+
+func runSign(cmd *cobra.Command, args []string) error {
+	keyPath := signKey
+	if keyPath == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return err
+		}
+		keyPath = filepath.Join(home, ".ssh", "id_ed25519")
+	}
+
+	signer, err := internal.LoadSigner(keyPath)
+	if err != nil {
+		return fmt.Errorf("failed to load key: %w", err)
+	}
+
+	archive, err := internal.ReadArchive(args[0])
+	if err != nil {
+		return fmt.Errorf("failed to read archive: %w", err)
+	}
+
+	if err := internal.SignArchive(archive, signer); err != nil {
+		return err
+	}
+
+	output := signOutput
+	if output == "" {
+		output = args[0]
+	}
+
+	if output == "-" {
+		_, err = os.Stdout.Write(txtar.Format(archive))
+	} else {
+		err = internal.WriteArchiveFile(output, archive)
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to write output: %w", err)
+	}
+
+	fmt.Fprintf(os.Stderr, "Signed with %s\n", ssh.FingerprintSHA256(signer.PublicKey()))
+	return nil
+}
@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/phlv/txtar/internal"
+	"golang.org/x/tools/txtar"
+)
+
+var splitCmd = &cobra.Command{
+	Use:   "split [ARCHIVE]",
+	Short: "Split a txtar archive into numbered parts",
+	Long: `Split a txtar archive into numbered parts that each stay within a size,
+file count, or token budget. Reads from stdin if ARCHIVE is '-' or not specified.
+Files are never split unless --allow-split-files is given; split files are
+reassembled by merge and unpack.`,
+	RunE: runSplit,
+}
+
+var (
+	splitOpts      internal.SplitOptions
+	splitMaxBytes  string
+	splitPrefix    string
+	splitTokenizer string
+)
+
+func init() {
+	rootCmd.AddCommand(splitCmd)
+
+	splitCmd.Flags().StringVar(&splitMaxBytes, "max-bytes", "", "Maximum size of each part (e.g. 200k, 1M)")
+	splitCmd.Flags().IntVar(&splitOpts.MaxFiles, "max-files", 0, "Maximum number of files per part")
+	splitCmd.Flags().IntVar(&splitOpts.MaxTokens, "max-tokens", 0, "Maximum estimated tokens per part")
+	splitCmd.Flags().BoolVar(&splitOpts.AllowSplitFiles, "allow-split-files", false, "Split files that exceed the limit on their own")
+	splitCmd.Flags().StringVar(&splitTokenizer, "tokenizer", "bpe", "Token estimator for --max-tokens: bpe or chars")
+	splitCmd.Flags().StringVarP(&splitPrefix, "prefix", "o", "", "Output path prefix (default: archive name without extension)")
+}
+
+func runSplit(cmd *cobra.Command, args []string) error {
+	archivePath := "-"
+	if len(args) > 0 {
+		archivePath = args[0]
+	}
+
+	if splitMaxBytes != "" {
+		n, err := internal.ParseSize(splitMaxBytes)
+		if err != nil {
+			return fmt.Errorf("invalid --max-bytes: %w", err)
+		}
+		splitOpts.MaxBytes = n
+	}
+
+	tok, err := internal.NewTokenizer(splitTokenizer)
+	if err != nil {
+		return err
+	}
+	splitOpts.Tokenizer = tok
+
+	prefix := splitPrefix
+	if prefix == "" {
+		if archivePath == "-" {
+			prefix = "archive"
+		} else {
+			prefix = strings.TrimSuffix(archivePath, filepath.Ext(archivePath))
+		}
+	}
+
+	archive, err := internal.ReadArchive(archivePath)
+	if err != nil {
+		return fmt.Errorf("failed to read archive: %w", err)
+	}
+
+	parts, warnings, err := internal.Split(archive, splitOpts)
+	if err != nil {
+		return fmt.Errorf("split failed: %w", err)
+	}
+
+	for _, w := range warnings {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", w)
+	}
+
+	width := len(fmt.Sprint(len(parts)))
+	for i, part := range parts {
+		path := fmt.Sprintf("%s.part%0*d.txtar", prefix, width, i+1)
+		if err := os.WriteFile(path, txtar.Format(part), 0644); err != nil {
+			return fmt.Errorf("failed to write output: %w", err)
+		}
+		fmt.Fprintf(os.Stderr, "Wrote: %s (%d files)\n", path, len(part.Files))
+	}
+
+	return nil
+}
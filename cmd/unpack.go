@@ -2,7 +2,6 @@ package cmd
 
 import (
 	"fmt"
-	"io"
 	"os"
 
 	"github.com/spf13/cobra"
@@ -19,7 +18,13 @@ Reads from stdin if ARCHIVE is '-' or not specified.`,
 	RunE: runUnpack,
 }
 
-var unpackOpts internal.UnpackOptions
+var (
+	unpackOpts    internal.UnpackOptions
+	unpackPubkey  string
+	unpackFormat  string
+	unpackLenient bool
+	unpackNoHooks bool
+)
 
 func init() {
 	rootCmd.AddCommand(unpackCmd)
@@ -27,14 +32,29 @@ func init() {
 	unpackCmd.Flags().StringVarP(&unpackOpts.Dir, "dir", "C", ".", "Output directory")
 	unpackCmd.Flags().BoolVar(&unpackOpts.Backup, "backup", false, "Backup existing files before overwriting")
 	unpackCmd.Flags().BoolVar(&unpackOpts.DryRun, "dry-run", false, "Show operations without writing files")
+	unpackCmd.Flags().BoolVar(&unpackOpts.ShowDiff, "diff", false, "With --dry-run, show a unified diff for files that would change")
+	unpackCmd.Flags().BoolVar(&unpackOpts.Interactive, "interactive", false, "Ask before writing each new or changed file")
+	unpackCmd.Flags().BoolVar(&unpackOpts.Hunks, "hunks", false, "With --interactive, ask about each hunk of a changed file")
 	unpackCmd.Flags().BoolVar(&unpackOpts.NoOverwrite, "no-overwrite", false, "Fail if files exist (mutually exclusive with --backup)")
+	unpackCmd.Flags().BoolVar(&unpackOpts.Verify, "verify", false, "Check entries against the embedded manifest before extracting")
+	unpackCmd.Flags().BoolVar(&unpackOpts.Force, "force", false, "Extract even if verification fails")
+	unpackCmd.Flags().StringVar(&unpackFormat, "format", "txtar", "Input format: txtar, markdown, json, or auto")
+	unpackCmd.Flags().BoolVar(&unpackLenient, "lenient", false, "Repair archives mangled by chat tools (fences, prose, CRLF, marker spacing, smart quotes)")
+	unpackCmd.Flags().BoolVar(&unpackOpts.RequireSignature, "require-signature", false, "Refuse to extract unless the archive is signed by --pubkey")
+	unpackCmd.Flags().BoolVar(&unpackNoHooks, "no-hooks", false, "Do not run unpack.post hooks from the config file")
+	unpackCmd.Flags().StringVar(&unpackPubkey, "pubkey", "", "Trusted public key file for --require-signature")
+	unpackCmd.Flags().StringSliceVar(&unpackOpts.Deny, "deny", internal.DefaultDenyPatterns, "Refuse to write entries matching these glob patterns (--deny= to allow all)")
+	unpackCmd.Flags().StringSliceVarP(&unpackOpts.Include, "include", "i", []string{}, "Only extract entries matching these patterns (glob)")
+	unpackCmd.Flags().StringSliceVarP(&unpackOpts.Exclude, "exclude", "e", []string{}, "Skip entries matching these patterns (glob)")
+	unpackCmd.Flags().IntVar(&unpackOpts.StripComponents, "strip-components", 0, "Remove N leading path components from entry names")
+	unpackCmd.Flags().StringVar(&unpackOpts.Prefix, "prefix", "", "Prepend this directory to entry names")
+	unpackCmd.Flags().StringVar(&unpackOpts.CaseFold, "case-fold", "auto", "Treat names differing only in case as the same file: auto, on, or off")
 
 	viper.BindPFlag("unpack.backup", unpackCmd.Flags().Lookup("backup"))
 	viper.BindPFlag("unpack.dir", unpackCmd.Flags().Lookup("dir"))
 }
 
 func runUnpack(cmd *cobra.Command, args []string) error {
-	var data []byte
 	var err error
 
 	archivePath := "-"
@@ -51,24 +71,70 @@ func runUnpack(cmd *cobra.Command, args []string) error {
 		unpackOpts.Dir = viper.GetString("unpack.dir")
 	}
 
-	if archivePath == "-" {
-		data, err = io.ReadAll(os.Stdin)
-	} else {
-		data, err = os.ReadFile(archivePath)
+	// An untrusted config file may add deny patterns but not drop the
+	// defaults, so a checkout cannot let an archive write into .git.
+	if viper.IsSet("unpack.deny") && !cmd.Flags().Changed("deny") {
+		if trustedConfig() {
+			unpackOpts.Deny = viper.GetStringSlice("unpack.deny")
+		} else {
+			unpackOpts.Deny = append(append([]string(nil), internal.DefaultDenyPatterns...), viper.GetStringSlice("unpack.deny")...)
+		}
 	}
 
+	if unpackOpts.RequireSignature {
+		if unpackPubkey == "" {
+			return fmt.Errorf("--require-signature requires --pubkey")
+		}
+		unpackOpts.TrustedKeys, err = internal.LoadPublicKeys(unpackPubkey)
+		if err != nil {
+			return fmt.Errorf("failed to load public key: %w", err)
+		}
+	}
+
+	if !unpackNoHooks {
+		unpackOpts.PostHooks = hookCommands("unpack.post")
+	}
+
+	if unpackOpts.Hunks && !unpackOpts.Interactive {
+		return fmt.Errorf("--hunks requires --interactive")
+	}
+
+	// Stdin carries the archive, so prompts are read from the terminal.
+	if unpackOpts.Interactive && archivePath == "-" {
+		tty, err := os.Open("/dev/tty")
+		if err != nil {
+			return fmt.Errorf("--interactive needs a terminal when the archive is read from stdin: %w", err)
+		}
+		defer tty.Close()
+		unpackOpts.PromptIn = tty
+	}
+
+	data, err := internal.ReadArchiveData(archivePath)
 	if err != nil {
 		return fmt.Errorf("failed to read archive: %w", err)
 	}
 
-	archive := txtar.Parse(data)
+	var archive *txtar.Archive
+	if unpackLenient {
+		if unpackFormat != "txtar" {
+			return fmt.Errorf("--lenient only applies to txtar input")
+		}
+
+		var fixes []string
+		archive, fixes, err = internal.ParseLenient(data)
+		for _, fix := range fixes {
+			fmt.Fprintf(os.Stderr, "Lenient: %s\n", fix)
+		}
+	} else {
+		archive, err = internal.ParseArchiveAs(data, unpackFormat)
+	}
 
-	if err := internal.Unpack(archive, unpackOpts); err != nil {
-		return fmt.Errorf("unpack failed: %w", err)
+	if err != nil {
+		return fmt.Errorf("failed to parse archive: %w", err)
 	}
 
-	if !unpackOpts.DryRun {
-		fmt.Fprintf(os.Stderr, "Successfully unpacked %d files to %s\n", len(archive.Files), unpackOpts.Dir)
+	if err := internal.Unpack(archive, unpackOpts); err != nil {
+		return fmt.Errorf("unpack failed: %w", err)
 	}
 
 	return nil
@@ -0,0 +1,78 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/phlv/txtar/internal"
+	"golang.org/x/crypto/ssh"
+)
+
+var verifyCmd = &cobra.Command{
+	Use:   "verify [ARCHIVE]",
+	Short: "Check a txtar archive against its manifest and signature",
+	Long: `Check every entry of an archive against the SHA-256 manifest written by
+'pack --manifest'. Reports truncated, edited, added, and missing files.
+With --pubkey, also require a valid signature from one of the given keys.
+Reads from stdin if ARCHIVE is '-' or not specified.
+Exits with status 1 when verification fails.`,
+	RunE: runVerify,
+}
+
+var verifyPubkey string
+
+func init() {
+	rootCmd.AddCommand(verifyCmd)
+
+	verifyCmd.Flags().StringVar(&verifyPubkey, "pubkey", "", "Trusted public key file (PEM or authorized_keys format)")
+}
+
+func runVerify(cmd *cobra.Command, args []string) error {
+	archivePath := "-"
+	if len(args) > 0 {
+		archivePath = args[0]
+	}
+
+	archive, err := internal.ReadArchive(archivePath)
+	if err != nil {
+		return fmt.Errorf("failed to read archive: %w", err)
+	}
+
+	if verifyPubkey != "" {
+		keys, err := internal.LoadPublicKeys(verifyPubkey)
+		if err != nil {
+			return fmt.Errorf("failed to load public key: %w", err)
+		}
+
+		key, err := internal.VerifySignature(archive, keys)
+		if err != nil {
+			fmt.Println(err)
+			return silentExit(cmd, 1)
+		}
+		fmt.Printf("Signature OK: %s\n", ssh.FingerprintSHA256(key))
+
+		if _, err := internal.ParseManifest(archive.Comment); err != nil {
+			return nil
+		}
+	}
+
+	archive, err = internal.JoinSplitFiles(archive)
+	if err != nil {
+		return err
+	}
+
+	problems, err := internal.VerifyManifest(archive)
+	if err != nil {
+		return fmt.Errorf("verify failed: %w", err)
+	}
+
+	if len(problems) > 0 {
+		for _, p := range problems {
+			fmt.Println(p)
+		}
+		return silentExit(cmd, 1)
+	}
+
+	fmt.Printf("OK: %d files verified\n", len(archive.Files))
+	return nil
+}
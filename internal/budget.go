@@ -0,0 +1,95 @@
+package internal
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/bmatcuk/doublestar/v4"
+)
+
+func isTestFile(p string) bool {
+	base := path.Base(p)
+	ext := path.Ext(base)
+	stem := strings.TrimSuffix(base, ext)
+
+	switch {
+	case strings.HasSuffix(stem, "_test"), strings.HasSuffix(stem, ".test"), strings.HasSuffix(stem, ".spec"):
+		return true
+	case strings.HasPrefix(stem, "test_"):
+		return true
+	}
+
+	for _, dir := range strings.Split(path.Dir(p), "/") {
+		if dir == "test" || dir == "tests" || dir == "testdata" || dir == "__tests__" {
+			return true
+		}
+	}
+	return false
+}
+
+// priorityRank returns the position of the first priority glob matching p.
+// Unmatched files rank after all globs, and unmatched test files rank last.
+func priorityRank(p string, priority []string) int {
+	for i, pattern := range priority {
+		if m, _ := doublestar.Match(pattern, p); m {
+			return i
+		}
+	}
+	if isTestFile(p) {
+		return len(priority) + 1
+	}
+	return len(priority)
+}
+
+// applyTokenBudget drops files until the archive fits in opts.MaxTokens.
+// Files with the lowest priority go first, largest first within a rank.
+func applyTokenBudget(files []string, fileContents map[string][]byte, opts PackOptions) ([]string, error) {
+	tok, err := NewTokenizer(opts.Tokenizer)
+	if err != nil {
+		return nil, err
+	}
+
+	cost := make(map[string]int, len(files))
+	total := 0
+	for _, f := range files {
+		_, cost[f] = entryCost(tok, filepath.ToSlash(f), fileContents[f])
+		total += cost[f]
+	}
+
+	if total <= opts.MaxTokens {
+		return files, nil
+	}
+
+	candidates := append([]string(nil), files...)
+	sort.SliceStable(candidates, func(i, j int) bool {
+		ri, rj := priorityRank(filepath.ToSlash(candidates[i]), opts.Priority), priorityRank(filepath.ToSlash(candidates[j]), opts.Priority)
+		if ri != rj {
+			return ri > rj
+		}
+		return cost[candidates[i]] > cost[candidates[j]]
+	})
+
+	skipped := make(map[string]bool)
+	for _, f := range candidates {
+		if total <= opts.MaxTokens {
+			break
+		}
+		skipped[f] = true
+		total -= cost[f]
+		fmt.Fprintf(os.Stderr, "Skipped (token budget): %s (%d tokens)\n", f, cost[f])
+	}
+
+	var kept []string
+	for _, f := range files {
+		if !skipped[f] {
+			kept = append(kept, f)
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "Token budget: %d/%d tokens, %d files skipped\n", total, opts.MaxTokens, len(skipped))
+	return kept, nil
+}
@@ -0,0 +1,128 @@
+package internal
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+)
+
+type PackMetadata struct {
+	SourceDir string
+	GitCommit string
+	GitBranch string
+	GitDirty  bool
+	Mode      string
+	Time      string
+	Version   string
+	FileCount int
+	Include   []string
+	Exclude   []string
+
+	// Transforms lists the content transforms that changed files, with
+	// counts, e.g. "comments (12 files)".
+	Transforms []string
+}
+
+const DefaultCommentTemplate = `txtar pack of {{.SourceDir}}
+{{- if .GitCommit}}
+git: {{.GitCommit}}{{if .GitBranch}} ({{.GitBranch}}){{end}}{{if .GitDirty}} dirty{{end}}
+{{- end}}
+mode: {{.Mode}}
+files: {{.FileCount}}
+{{- if .Include}}
+include: {{join .Include ", "}}
+{{- end}}
+{{- if .Exclude}}
+exclude: {{join .Exclude ", "}}
+{{- end}}
+{{- if .Transforms}}
+transforms: {{join .Transforms ", "}}
+{{- end}}
+packed: {{.Time}} by txtar {{.Version}}
+`
+
+func packMode(opts PackOptions) string {
+	switch {
+	case !opts.Git:
+		return "dir"
+	case opts.Diff:
+		return "--diff"
+	case opts.Commit != "":
+		return "--commit " + opts.Commit
+	case opts.Since > 0:
+		return fmt.Sprintf("--since %d", opts.Since)
+	case opts.Staged:
+		return "--staged"
+	case opts.Worktree:
+		return "--worktree"
+	default:
+		return "HEAD"
+	}
+}
+
+func collectMetadata(opts PackOptions, fileCount int) PackMetadata {
+	dir := opts.Dir
+	if dir == "" {
+		dir = "."
+	}
+	if abs, err := filepath.Abs(dir); err == nil {
+		dir = abs
+	}
+
+	version := opts.Version
+	if version == "" {
+		version = "dev"
+	}
+
+	meta := PackMetadata{
+		SourceDir: dir,
+		Mode:      packMode(opts),
+		Time:      time.Now().UTC().Format(time.RFC3339),
+		Version:   version,
+		FileCount: fileCount,
+		Include:   opts.Include,
+		Exclude:   opts.Exclude,
+	}
+
+	// Git details are best effort; a plain directory simply has none.
+	repo, err := git.PlainOpenWithOptions(dir, &git.PlainOpenOptions{DetectDotGit: true})
+	if err != nil {
+		return meta
+	}
+
+	if head, err := repo.Head(); err == nil {
+		meta.GitCommit = head.Hash().String()
+		if head.Name().IsBranch() {
+			meta.GitBranch = head.Name().Short()
+		}
+	}
+
+	if w, err := repo.Worktree(); err == nil {
+		if status, err := w.Status(); err == nil {
+			meta.GitDirty = !status.IsClean()
+		}
+	}
+
+	return meta
+}
+
+func RenderComment(text string, meta PackMetadata) ([]byte, error) {
+	tmpl, err := template.New("comment").Funcs(template.FuncMap{
+		"join": strings.Join,
+	}).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid comment template: %w", err)
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, meta); err != nil {
+		return nil, fmt.Errorf("failed to render comment: %w", err)
+	}
+
+	return fixNL(buf.Bytes()), nil
+}
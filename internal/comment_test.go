@@ -0,0 +1,60 @@
+package internal
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRenderDefaultComment(t *testing.T) {
+	meta := PackMetadata{
+		SourceDir: "/src",
+		GitCommit: "abc123",
+		GitBranch: "main",
+		GitDirty:  true,
+		Mode:      "--since 3",
+		Time:      "2024-01-01T00:00:00Z",
+		Version:   "v1.2.3",
+		FileCount: 4,
+		Exclude:   []string{"*.log", "vendor/**"},
+	}
+
+	comment, err := RenderComment(DefaultCommentTemplate, meta)
+	if err != nil {
+		t.Fatalf("RenderComment failed: %v", err)
+	}
+
+	want := `txtar pack of /src
+git: abc123 (main) dirty
+mode: --since 3
+files: 4
+exclude: *.log, vendor/**
+packed: 2024-01-01T00:00:00Z by txtar v1.2.3
+`
+	if string(comment) != want {
+		t.Errorf("Unexpected comment:\n%s", comment)
+	}
+}
+
+func TestPackCommentTemplate(t *testing.T) {
+	tmpDir := t.TempDir()
+	os.WriteFile(filepath.Join(tmpDir, "a.txt"), []byte("a"), 0644)
+
+	archive, _, err := Pack(context.Background(), PackOptions{
+		Dir:             tmpDir,
+		CommentTemplate: "{{.FileCount}} files via {{.Mode}}",
+	})
+	if err != nil {
+		t.Fatalf("Pack failed: %v", err)
+	}
+
+	if string(archive.Comment) != "1 files via dir\n" {
+		t.Errorf("Unexpected comment: %q", archive.Comment)
+	}
+
+	if _, _, err := Pack(context.Background(), PackOptions{Dir: tmpDir, CommentTemplate: "{{.Nope"}); err == nil || !strings.Contains(err.Error(), "template") {
+		t.Errorf("Expected template error, got %v", err)
+	}
+}
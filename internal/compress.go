@@ -0,0 +1,230 @@
+package internal
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"filippo.io/age"
+	"filippo.io/age/agessh"
+	"filippo.io/age/armor"
+	"github.com/klauspost/compress/zstd"
+)
+
+var (
+	gzipMagic  = []byte{0x1f, 0x8b}
+	zstdMagic  = []byte{0x28, 0xb5, 0x2f, 0xfd}
+	ageMagic   = []byte("age-encryption.org/")
+	armorMagic = []byte(armor.Header)
+)
+
+var identities []age.Identity
+
+// SetIdentities sets the age identities tried when an encrypted archive is opened.
+func SetIdentities(ids []age.Identity) {
+	identities = ids
+}
+
+// LoadIdentities reads age identities from an identity file (AGE-SECRET-KEY
+// lines or an SSH private key) and, if passphrase is set, adds a scrypt identity.
+func LoadIdentities(path, passphrase string) ([]age.Identity, error) {
+	var ids []age.Identity
+
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		if bytes.Contains(data, []byte("PRIVATE KEY")) {
+			id, err := agessh.ParseIdentity(data)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse identity %s: %w", path, err)
+			}
+			ids = append(ids, id)
+		} else {
+			parsed, err := age.ParseIdentities(bytes.NewReader(data))
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse identity %s: %w", path, err)
+			}
+			ids = append(ids, parsed...)
+		}
+	}
+
+	if passphrase != "" {
+		id, err := age.NewScryptIdentity(passphrase)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, nil
+}
+
+// ParseRecipients accepts age1... recipients and SSH public keys.
+func ParseRecipients(values []string, passphrase string) ([]age.Recipient, error) {
+	var recipients []age.Recipient
+
+	for _, v := range values {
+		var r age.Recipient
+		var err error
+		if strings.HasPrefix(v, "age1") {
+			r, err = age.ParseX25519Recipient(v)
+		} else {
+			r, err = agessh.ParseRecipient(v)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid recipient %q: %w", v, err)
+		}
+		recipients = append(recipients, r)
+	}
+
+	if passphrase != "" {
+		if len(recipients) > 0 {
+			return nil, fmt.Errorf("passphrase encryption cannot be combined with recipients")
+		}
+		r, err := age.NewScryptRecipient(passphrase)
+		if err != nil {
+			return nil, err
+		}
+		recipients = append(recipients, r)
+	}
+
+	return recipients, nil
+}
+
+// decodedReader reads the innermost layer found by decodeReader. Close
+// releases the decompressors but not the underlying reader.
+type decodedReader struct {
+	io.Reader
+	closers []io.Closer
+}
+
+func (d *decodedReader) Close() error {
+	var err error
+	for i := len(d.closers) - 1; i >= 0; i-- {
+		if cerr := d.closers[i].Close(); err == nil {
+			err = cerr
+		}
+	}
+	d.closers = nil
+	return err
+}
+
+// decodeReader peels off any age encryption and gzip/zstd compression
+// layers, detected by their magic bytes. The caller must close the result
+// to stop the zstd decoder's goroutines.
+func decodeReader(r io.Reader) (io.ReadCloser, error) {
+	d := &decodedReader{}
+	for {
+		br := bufio.NewReader(r)
+		head, _ := br.Peek(len(armorMagic))
+
+		switch {
+		case bytes.HasPrefix(head, armorMagic):
+			r = armor.NewReader(br)
+		case bytes.HasPrefix(head, ageMagic):
+			r = br
+		case bytes.HasPrefix(head, gzipMagic):
+			gz, err := gzip.NewReader(br)
+			if err != nil {
+				d.Close()
+				return nil, err
+			}
+			d.closers = append(d.closers, gz)
+			r = gz
+			continue
+		case bytes.HasPrefix(head, zstdMagic):
+			zr, err := zstd.NewReader(br)
+			if err != nil {
+				d.Close()
+				return nil, err
+			}
+			rc := zr.IOReadCloser()
+			d.closers = append(d.closers, rc)
+			r = rc
+			continue
+		default:
+			d.Reader = br
+			return d, nil
+		}
+
+		if len(identities) == 0 {
+			d.Close()
+			return nil, fmt.Errorf("archive is encrypted; use --identity or set TXTAR_PASSPHRASE")
+		}
+		dec, err := age.Decrypt(r, identities...)
+		if err != nil {
+			d.Close()
+			return nil, fmt.Errorf("failed to decrypt archive: %w", err)
+		}
+		r = dec
+	}
+}
+
+// CompressionForPath picks the compression implied by a file name.
+func CompressionForPath(path string) string {
+	path = strings.TrimSuffix(path, ".age")
+	switch {
+	case strings.HasSuffix(path, ".gz"):
+		return "gzip"
+	case strings.HasSuffix(path, ".zst"):
+		return "zstd"
+	default:
+		return "none"
+	}
+}
+
+// EncodeArchive compresses and optionally encrypts serialized archive data.
+func EncodeArchive(data []byte, compression string, recipients []age.Recipient) ([]byte, error) {
+	var buf bytes.Buffer
+	var w io.WriteCloser = nopWriteCloser{&buf}
+
+	if len(recipients) > 0 {
+		enc, err := age.Encrypt(&buf, recipients...)
+		if err != nil {
+			return nil, err
+		}
+		w = enc
+	}
+
+	inner := w
+	switch compression {
+	case "", "none":
+	case "gzip":
+		w = gzip.NewWriter(inner)
+	case "zstd":
+		zw, err := zstd.NewWriter(inner)
+		if err != nil {
+			return nil, err
+		}
+		w = zw
+	default:
+		return nil, fmt.Errorf("unknown compression %q (want none, gzip, or zstd)", compression)
+	}
+
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if w != inner {
+		if err := w.Close(); err != nil {
+			return nil, err
+		}
+	}
+	if err := inner.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
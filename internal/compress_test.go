@@ -0,0 +1,101 @@
+package internal
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"filippo.io/age"
+	"golang.org/x/tools/txtar"
+)
+
+func TestEncodeArchiveRoundTrip(t *testing.T) {
+	plain := txtar.Format(&txtar.Archive{Files: []txtar.File{{Name: "a.txt", Data: []byte("hello\n")}}})
+
+	id, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatalf("GenerateX25519Identity failed: %v", err)
+	}
+	SetIdentities([]age.Identity{id})
+	defer SetIdentities(nil)
+
+	for _, compression := range []string{"none", "gzip", "zstd"} {
+		for _, recipients := range [][]age.Recipient{nil, {id.Recipient()}} {
+			encoded, err := EncodeArchive(plain, compression, recipients)
+			if err != nil {
+				t.Fatalf("EncodeArchive(%s) failed: %v", compression, err)
+			}
+
+			r, err := decodeReader(bytes.NewReader(encoded))
+			if err != nil {
+				t.Fatalf("decodeReader(%s) failed: %v", compression, err)
+			}
+
+			got, err := io.ReadAll(r)
+			r.Close()
+			if err != nil || !bytes.Equal(got, plain) {
+				t.Errorf("Round trip with %s (encrypted=%v) = %q, %v", compression, recipients != nil, got, err)
+			}
+		}
+	}
+}
+
+func TestWriteArchiveFileCompressesByExtension(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "a.txtar.gz")
+	archive := &txtar.Archive{Files: []txtar.File{{Name: "a.txt", Data: []byte("hello\n")}}}
+
+	if err := WriteArchiveFile(path, archive); err != nil {
+		t.Fatalf("WriteArchiveFile failed: %v", err)
+	}
+
+	raw, _ := os.ReadFile(path)
+	if !bytes.HasPrefix(raw, gzipMagic) {
+		t.Errorf("Expected gzip output")
+	}
+
+	got, err := ReadArchive(path)
+	if err != nil || len(got.Files) != 1 || string(got.Files[0].Data) != "hello\n" {
+		t.Errorf("ReadArchive = %+v, %v", got, err)
+	}
+
+	if CompressionForPath("x.txtar.zst.age") != "zstd" || CompressionForPath("x.txtar") != "none" {
+		t.Error("Unexpected compression for path")
+	}
+}
+
+func TestOpenArchiveZstdReleasesDecoder(t *testing.T) {
+	// Several blocks, so that the decoder is still busy after one read.
+	var data []byte
+	for i := 0; len(data) < 4<<20; i++ {
+		data = fmt.Appendf(data, "line %d\n", i*7919%100003)
+	}
+
+	path := filepath.Join(t.TempDir(), "a.txtar.zst")
+	archive := &txtar.Archive{Files: []txtar.File{{Name: "a.txt", Data: data}}}
+	if err := WriteArchiveFile(path, archive); err != nil {
+		t.Fatalf("WriteArchiveFile failed: %v", err)
+	}
+
+	before := runtime.NumGoroutine()
+	for i := 0; i < 20; i++ {
+		rc, err := OpenArchive(path)
+		if err != nil {
+			t.Fatalf("OpenArchive failed: %v", err)
+		}
+		rc.Read(make([]byte, 1))
+		rc.Close()
+	}
+
+	// Decoder goroutines exit shortly after Close.
+	for deadline := time.Now().Add(2 * time.Second); runtime.NumGoroutine() > before && time.Now().Before(deadline); {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if n := runtime.NumGoroutine(); n > before {
+		t.Errorf("Leaked %d goroutines", n-before)
+	}
+}
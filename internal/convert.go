@@ -0,0 +1,355 @@
+package internal
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"golang.org/x/tools/txtar"
+)
+
+type ConvertOptions struct {
+	From   string
+	To     string
+	Binary string
+}
+
+type convertEntry struct {
+	Name string
+	Data []byte
+	Mode os.FileMode
+}
+
+const base64Suffix = " [base64]"
+
+// DetectFormat guesses an archive format from a path. Existing directories
+// and paths ending in a separator are "dir".
+func DetectFormat(path string) string {
+	if strings.HasSuffix(path, "/") || strings.HasSuffix(path, string(filepath.Separator)) {
+		return "dir"
+	}
+	if info, err := os.Stat(path); err == nil && info.IsDir() {
+		return "dir"
+	}
+
+	lower := strings.ToLower(path)
+	switch {
+	case strings.HasSuffix(lower, ".tar.gz"), strings.HasSuffix(lower, ".tgz"):
+		return "tar.gz"
+	case strings.HasSuffix(lower, ".tar"):
+		return "tar"
+	case strings.HasSuffix(lower, ".zip"):
+		return "zip"
+	default:
+		return "txtar"
+	}
+}
+
+func Convert(in, out string, opts ConvertOptions) error {
+	if opts.From == "" {
+		opts.From = DetectFormat(in)
+	}
+	if opts.To == "" {
+		opts.To = DetectFormat(out)
+	}
+	if opts.Binary == "" {
+		opts.Binary = "skip"
+	}
+
+	entries, err := readEntries(in, opts.From)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", in, err)
+	}
+
+	for _, e := range entries {
+		if err := validateEntryName(e.Name); err != nil {
+			return err
+		}
+	}
+
+	switch opts.To {
+	case "txtar":
+		archive, err := entriesToArchive(entries, opts.Binary)
+		if err != nil {
+			return err
+		}
+		data, err := EncodeArchive(txtar.Format(archive), CompressionForPath(out), nil)
+		if err != nil {
+			return err
+		}
+		return writeOutput(out, data)
+	case "dir":
+		return writeDir(out, entries)
+	case "tar", "tar.gz":
+		var buf bytes.Buffer
+		if err := writeTar(&buf, entries, opts.To == "tar.gz"); err != nil {
+			return err
+		}
+		return writeOutput(out, buf.Bytes())
+	case "zip":
+		var buf bytes.Buffer
+		if err := writeZip(&buf, entries); err != nil {
+			return err
+		}
+		return writeOutput(out, buf.Bytes())
+	default:
+		return fmt.Errorf("unknown output format %q (want txtar, tar, tar.gz, zip, or dir)", opts.To)
+	}
+}
+
+func writeOutput(path string, data []byte) error {
+	if path == "-" {
+		_, err := os.Stdout.Write(data)
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+func readEntries(path, format string) ([]convertEntry, error) {
+	switch format {
+	case "txtar":
+		archive, err := ReadArchive(path)
+		if err != nil {
+			return nil, err
+		}
+		archive, err = JoinSplitFiles(archive)
+		if err != nil {
+			return nil, err
+		}
+		return archiveToEntries(archive)
+	case "dir":
+		return readDir(path)
+	case "tar", "tar.gz":
+		rc, err := OpenArchive(path)
+		if err != nil {
+			return nil, err
+		}
+		defer rc.Close()
+		return readTar(rc)
+	case "zip":
+		return readZip(path)
+	default:
+		return nil, fmt.Errorf("unknown input format %q (want txtar, tar, tar.gz, zip, or dir)", format)
+	}
+}
+
+func archiveToEntries(archive *txtar.Archive) ([]convertEntry, error) {
+	var entries []convertEntry
+	for _, f := range archive.Files {
+		e := convertEntry{Name: f.Name, Data: f.Data, Mode: 0644}
+		if strings.HasSuffix(f.Name, base64Suffix) {
+			data, err := base64.StdEncoding.DecodeString(string(bytes.Join(bytes.Fields(f.Data), nil)))
+			if err != nil {
+				return nil, fmt.Errorf("invalid base64 entry %q: %w", f.Name, err)
+			}
+			e.Name = strings.TrimSuffix(f.Name, base64Suffix)
+			e.Data = data
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+func entriesToArchive(entries []convertEntry, binary string) (*txtar.Archive, error) {
+	archive := &txtar.Archive{}
+	for _, e := range entries {
+		if !isBinaryContent(e.Data) {
+			archive.Files = append(archive.Files, txtar.File{Name: e.Name, Data: e.Data})
+			continue
+		}
+
+		switch binary {
+		case "skip":
+			fmt.Fprintf(os.Stderr, "Skipped binary: %s\n", e.Name)
+		case "encode":
+			archive.Files = append(archive.Files, txtar.File{Name: e.Name + base64Suffix, Data: wrapBase64(e.Data)})
+		case "include":
+			archive.Files = append(archive.Files, txtar.File{Name: e.Name, Data: e.Data})
+		default:
+			return nil, fmt.Errorf("unknown binary policy %q (want skip, encode, or include)", binary)
+		}
+	}
+	return archive, nil
+}
+
+func wrapBase64(data []byte) []byte {
+	encoded := base64.StdEncoding.EncodeToString(data)
+	var buf bytes.Buffer
+	for len(encoded) > 76 {
+		buf.WriteString(encoded[:76])
+		buf.WriteByte('\n')
+		encoded = encoded[76:]
+	}
+	buf.WriteString(encoded)
+	buf.WriteByte('\n')
+	return buf.Bytes()
+}
+
+func cleanEntryName(name string) string {
+	name = filepath.ToSlash(name)
+	return strings.TrimPrefix(name, "./")
+}
+
+// defaultMode keeps the permission bits of m, falling back to 0644 for
+// archives that carry no mode information.
+func defaultMode(m os.FileMode) os.FileMode {
+	if m.Perm() == 0 {
+		return 0644
+	}
+	return m.Perm()
+}
+
+func readDir(dir string) ([]convertEntry, error) {
+	var entries []convertEntry
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		relPath, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		entries = append(entries, convertEntry{Name: filepath.ToSlash(relPath), Data: content, Mode: info.Mode().Perm()})
+		return nil
+	})
+	return entries, err
+}
+
+func writeDir(dir string, entries []convertEntry) error {
+	for _, e := range entries {
+		rel := filepath.FromSlash(e.Name)
+		if err := confine(dir, rel); err != nil {
+			return fmt.Errorf("unsafe path %q: %w", e.Name, err)
+		}
+
+		target := filepath.Join(dir, rel)
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(target, e.Data, e.Mode.Perm()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func readTar(r io.Reader) ([]convertEntry, error) {
+	var entries []convertEntry
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, convertEntry{Name: cleanEntryName(hdr.Name), Data: data, Mode: defaultMode(os.FileMode(hdr.Mode))})
+	}
+	return entries, nil
+}
+
+func writeTar(w io.Writer, entries []convertEntry, compress bool) error {
+	var gz *gzip.Writer
+	if compress {
+		gz = gzip.NewWriter(w)
+		w = gz
+	}
+
+	tw := tar.NewWriter(w)
+	now := time.Now()
+	for _, e := range entries {
+		hdr := &tar.Header{
+			Name:    e.Name,
+			Mode:    int64(e.Mode.Perm()),
+			Size:    int64(len(e.Data)),
+			ModTime: now,
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if _, err := tw.Write(e.Data); err != nil {
+			return err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	if gz != nil {
+		return gz.Close()
+	}
+	return nil
+}
+
+func readZip(path string) ([]convertEntry, error) {
+	zr, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+
+	var entries []convertEntry
+	for _, f := range zr.File {
+		if !f.Mode().IsRegular() {
+			continue
+		}
+
+		rc, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		data, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		entries = append(entries, convertEntry{Name: cleanEntryName(f.Name), Data: data, Mode: defaultMode(f.Mode())})
+	}
+	return entries, nil
+}
+
+func writeZip(w io.Writer, entries []convertEntry) error {
+	zw := zip.NewWriter(w)
+	now := time.Now()
+	for _, e := range entries {
+		hdr := &zip.FileHeader{Name: e.Name, Method: zip.Deflate, Modified: now}
+		hdr.SetMode(e.Mode.Perm())
+		fw, err := zw.CreateHeader(hdr)
+		if err != nil {
+			return err
+		}
+		if _, err := fw.Write(e.Data); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
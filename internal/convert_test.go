@@ -0,0 +1,99 @@
+package internal
+
+import (
+	"archive/tar"
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/tools/txtar"
+)
+
+func TestConvertRoundTrip(t *testing.T) {
+	tmpDir := t.TempDir()
+	src := filepath.Join(tmpDir, "src")
+	os.MkdirAll(filepath.Join(src, "bin"), 0755)
+	os.WriteFile(filepath.Join(src, "run.sh"), []byte("#!/bin/sh\n"), 0755)
+	os.WriteFile(filepath.Join(src, "bin", "blob"), []byte{0, 1, 2, 3}, 0644)
+
+	tgz := filepath.Join(tmpDir, "a.tar.gz")
+	if err := Convert(src, tgz, ConvertOptions{}); err != nil {
+		t.Fatalf("dir -> tar.gz failed: %v", err)
+	}
+
+	zipPath := filepath.Join(tmpDir, "a.zip")
+	if err := Convert(tgz, zipPath, ConvertOptions{}); err != nil {
+		t.Fatalf("tar.gz -> zip failed: %v", err)
+	}
+
+	txt := filepath.Join(tmpDir, "a.txtar")
+	if err := Convert(zipPath, txt, ConvertOptions{Binary: "encode"}); err != nil {
+		t.Fatalf("zip -> txtar failed: %v", err)
+	}
+
+	archive, err := txtar.ParseFile(txt)
+	if err != nil {
+		t.Fatalf("ParseFile failed: %v", err)
+	}
+	if len(archive.Files) != 2 || archive.Files[0].Name != "bin/blob [base64]" {
+		t.Fatalf("Unexpected txtar entries: %+v", archive.Files)
+	}
+
+	out := filepath.Join(tmpDir, "out") + "/"
+	if err := Convert(txt, out, ConvertOptions{}); err != nil {
+		t.Fatalf("txtar -> dir failed: %v", err)
+	}
+
+	blob, _ := os.ReadFile(filepath.Join(tmpDir, "out", "bin", "blob"))
+	if !bytes.Equal(blob, []byte{0, 1, 2, 3}) {
+		t.Errorf("Binary file not restored: %v", blob)
+	}
+
+	if err := Convert(zipPath, filepath.Join(tmpDir, "zip-out")+"/", ConvertOptions{}); err != nil {
+		t.Fatalf("zip -> dir failed: %v", err)
+	}
+	info, err := os.Stat(filepath.Join(tmpDir, "zip-out", "run.sh"))
+	if err != nil || info.Mode().Perm() != 0755 {
+		t.Errorf("Expected mode 0755 to survive, got %v, %v", info, err)
+	}
+}
+
+func TestConvertRejectsTraversal(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	tw.WriteHeader(&tar.Header{Name: "../evil", Mode: 0644, Size: 1})
+	tw.Write([]byte("x"))
+	tw.Close()
+
+	in := filepath.Join(tmpDir, "evil.tar")
+	os.WriteFile(in, buf.Bytes(), 0644)
+
+	if err := Convert(in, filepath.Join(tmpDir, "out.txtar"), ConvertOptions{}); err == nil {
+		t.Error("Expected traversal entry to be rejected")
+	}
+}
+
+func TestConvertDirRejectsSymlinkEscape(t *testing.T) {
+	tmpDir := t.TempDir()
+	outside := filepath.Join(tmpDir, "outside")
+	os.MkdirAll(outside, 0755)
+
+	out := filepath.Join(tmpDir, "out")
+	os.MkdirAll(out, 0755)
+	if err := os.Symlink(outside, filepath.Join(out, "link")); err != nil {
+		t.Skipf("Symlinks not supported: %v", err)
+	}
+
+	in := filepath.Join(tmpDir, "in.txtar")
+	os.WriteFile(in, txtar.Format(&txtar.Archive{Files: []txtar.File{{Name: "link/evil.txt", Data: []byte("x\n")}}}), 0644)
+
+	if err := Convert(in, out+"/", ConvertOptions{}); err == nil {
+		t.Error("Expected write through a symlink to be rejected")
+	}
+	if _, err := os.Stat(filepath.Join(outside, "evil.txt")); err == nil {
+		t.Error("File written outside the output directory")
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/sergi/go-diff/diffmatchpatch"
 	"golang.org/x/tools/txtar"
@@ -34,23 +35,20 @@ func Diff(opts DiffOptions) ([]FileDiff, error) {
 			return nil, fmt.Errorf("failed to read directory %q: %w", opts.Left, err)
 		}
 
-		rightData, err := os.ReadFile(opts.Right)
+		rightArchive, err = ReadArchive(opts.Right)
 		if err != nil {
 			return nil, fmt.Errorf("failed to read archive %q: %w", opts.Right, err)
 		}
-		rightArchive = txtar.Parse(rightData)
 	} else {
-		leftData, err := os.ReadFile(opts.Left)
+		leftArchive, err = ReadArchive(opts.Left)
 		if err != nil {
 			return nil, fmt.Errorf("failed to read archive %q: %w", opts.Left, err)
 		}
-		leftArchive = txtar.Parse(leftData)
 
-		rightData, err := os.ReadFile(opts.Right)
+		rightArchive, err = ReadArchive(opts.Right)
 		if err != nil {
 			return nil, fmt.Errorf("failed to read archive %q: %w", opts.Right, err)
 		}
-		rightArchive = txtar.Parse(rightData)
 	}
 
 	return compareArchives(leftArchive, rightArchive), nil
@@ -150,3 +148,115 @@ func PrintDiff(w io.Writer, diff FileDiff, showContent bool) {
 		}
 	}
 }
+
+type diffLine struct {
+	Op   byte
+	Text string
+}
+
+type diffHunk struct {
+	OldStart, OldLines int
+	NewStart, NewLines int
+	Lines              []diffLine
+	Index              int // position of Lines[0] in the full edit script
+}
+
+// lineDiff returns the line-level edit script turning a into b. Ops are
+// ' ' for context, '-' for removed and '+' for added lines.
+func lineDiff(a, b string) []diffLine {
+	dmp := diffmatchpatch.New()
+	ca, cb, lines := dmp.DiffLinesToChars(a, b)
+	diffs := dmp.DiffCharsToLines(dmp.DiffMain(ca, cb, false), lines)
+
+	var result []diffLine
+	for _, d := range diffs {
+		op := byte(' ')
+		switch d.Type {
+		case diffmatchpatch.DiffDelete:
+			op = '-'
+		case diffmatchpatch.DiffInsert:
+			op = '+'
+		}
+		for _, text := range strings.SplitAfter(d.Text, "\n") {
+			if text != "" {
+				result = append(result, diffLine{Op: op, Text: text})
+			}
+		}
+	}
+	return result
+}
+
+// diffHunks groups changed lines with up to context lines around them.
+// Changes separated by no more than 2*context unchanged lines share a hunk.
+func diffHunks(lines []diffLine, context int) []diffHunk {
+	oldPos := make([]int, len(lines)+1)
+	newPos := make([]int, len(lines)+1)
+	for i, l := range lines {
+		oldPos[i+1], newPos[i+1] = oldPos[i], newPos[i]
+		if l.Op != '+' {
+			oldPos[i+1]++
+		}
+		if l.Op != '-' {
+			newPos[i+1]++
+		}
+	}
+
+	var hunks []diffHunk
+	for i := 0; i < len(lines); i++ {
+		if lines[i].Op == ' ' {
+			continue
+		}
+
+		start := max(0, i-context)
+		last := i
+		for j := i + 1; j < len(lines) && j-last <= 2*context+1; j++ {
+			if lines[j].Op != ' ' {
+				last = j
+			}
+		}
+		end := min(len(lines), last+context+1)
+
+		h := diffHunk{
+			OldStart: oldPos[start],
+			OldLines: oldPos[end] - oldPos[start],
+			NewStart: newPos[start],
+			NewLines: newPos[end] - newPos[start],
+			Lines:    lines[start:end],
+			Index:    start,
+		}
+		if h.OldLines > 0 {
+			h.OldStart++
+		}
+		if h.NewLines > 0 {
+			h.NewStart++
+		}
+		hunks = append(hunks, h)
+		i = end - 1
+	}
+	return hunks
+}
+
+func writeHunk(w io.Writer, h diffHunk) {
+	fmt.Fprintf(w, "@@ -%d,%d +%d,%d @@\n", h.OldStart, h.OldLines, h.NewStart, h.NewLines)
+	for _, l := range h.Lines {
+		fmt.Fprintf(w, "%c%s", l.Op, l.Text)
+		if !strings.HasSuffix(l.Text, "\n") {
+			fmt.Fprint(w, "\n\\ No newline at end of file\n")
+		}
+	}
+}
+
+// UnifiedDiff returns a unified diff from old to new with three lines of
+// context, or "" if they are equal.
+func UnifiedDiff(oldName, newName string, old, new []byte) string {
+	if bytes.Equal(old, new) {
+		return ""
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "--- %s\n+++ %s\n", oldName, newName)
+	for _, h := range diffHunks(lineDiff(string(old), string(new)), 3) {
+		writeHunk(&buf, h)
+	}
+	return buf.String()
+}
@@ -0,0 +1,146 @@
+package internal
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/bmatcuk/doublestar/v4"
+	"golang.org/x/tools/txtar"
+)
+
+func findFile(archive *txtar.Archive, name string) int {
+	for i, f := range archive.Files {
+		if f.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+func validateEntryName(name string) error {
+	if name == "" {
+		return fmt.Errorf("empty file name")
+	}
+	if err := validatePath(filepath.FromSlash(name)); err != nil {
+		return fmt.Errorf("invalid path %q: %w", name, err)
+	}
+	return nil
+}
+
+func AddFile(archive *txtar.Archive, name string, data []byte) error {
+	if err := validateEntryName(name); err != nil {
+		return err
+	}
+	if findFile(archive, name) >= 0 {
+		return fmt.Errorf("file already exists in archive: %s (use replace)", name)
+	}
+
+	archive.Files = append(archive.Files, txtar.File{Name: name, Data: data})
+	return nil
+}
+
+func ReplaceFile(archive *txtar.Archive, name string, data []byte) error {
+	i := findFile(archive, name)
+	if i < 0 {
+		return fmt.Errorf("file not found in archive: %s", name)
+	}
+
+	archive.Files[i].Data = data
+	return nil
+}
+
+// RemoveFiles removes every entry matching one of patterns, which may be
+// exact names or glob patterns. Each pattern must match at least one entry.
+func RemoveFiles(archive *txtar.Archive, patterns []string) ([]string, error) {
+	matchedPattern := make([]bool, len(patterns))
+	var removed []string
+	var kept []txtar.File
+
+	for _, f := range archive.Files {
+		remove := false
+		for i, pattern := range patterns {
+			if pattern == f.Name {
+				matchedPattern[i] = true
+				remove = true
+			} else if m, _ := doublestar.Match(pattern, f.Name); m {
+				matchedPattern[i] = true
+				remove = true
+			}
+		}
+
+		if remove {
+			removed = append(removed, f.Name)
+		} else {
+			kept = append(kept, f)
+		}
+	}
+
+	for i, matched := range matchedPattern {
+		if !matched {
+			return nil, fmt.Errorf("file not found in archive: %s", patterns[i])
+		}
+	}
+
+	archive.Files = kept
+	return removed, nil
+}
+
+func RenameFile(archive *txtar.Archive, oldName, newName string) error {
+	i := findFile(archive, oldName)
+	if i < 0 {
+		return fmt.Errorf("file not found in archive: %s", oldName)
+	}
+	if err := validateEntryName(newName); err != nil {
+		return err
+	}
+	if oldName != newName && findFile(archive, newName) >= 0 {
+		return fmt.Errorf("file already exists in archive: %s", newName)
+	}
+
+	archive.Files[i].Name = newName
+	return nil
+}
+
+// WriteArchiveFile replaces path atomically by writing to a temporary file in
+// the same directory and renaming it into place.
+func WriteArchiveFile(path string, archive *txtar.Archive) error {
+	if strings.HasSuffix(path, ".age") {
+		return fmt.Errorf("cannot rewrite encrypted archive %s in place", path)
+	}
+
+	data, err := EncodeArchive(txtar.Format(archive), CompressionForPath(path), nil)
+	if err != nil {
+		return err
+	}
+
+	mode := os.FileMode(0644)
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+	defer os.Remove(tmpPath)
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmpPath, mode); err != nil {
+		return err
+	}
+
+	return os.Rename(tmpPath, path)
+}
@@ -0,0 +1,55 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/tools/txtar"
+)
+
+func TestEditPreservesCommentAndOrder(t *testing.T) {
+	archive := txtar.Parse([]byte("keep me\n-- b.txt --\nb\n-- a.txt --\na\n-- c.log --\nc\n"))
+
+	if err := AddFile(archive, "d.txt", []byte("d\n")); err != nil {
+		t.Fatalf("AddFile failed: %v", err)
+	}
+	if err := AddFile(archive, "a.txt", nil); err == nil {
+		t.Error("Expected error adding duplicate name")
+	}
+	if err := AddFile(archive, "../x", nil); err == nil {
+		t.Error("Expected error adding traversal path")
+	}
+	if err := ReplaceFile(archive, "a.txt", []byte("A\n")); err != nil {
+		t.Fatalf("ReplaceFile failed: %v", err)
+	}
+	if err := RenameFile(archive, "b.txt", "z/b.txt"); err != nil {
+		t.Fatalf("RenameFile failed: %v", err)
+	}
+	if _, err := RemoveFiles(archive, []string{"*.log"}); err != nil {
+		t.Fatalf("RemoveFiles failed: %v", err)
+	}
+	if _, err := RemoveFiles(archive, []string{"missing.txt"}); err == nil {
+		t.Error("Expected error removing missing file")
+	}
+
+	path := filepath.Join(t.TempDir(), "out.txtar")
+	if err := WriteArchiveFile(path, archive); err != nil {
+		t.Fatalf("WriteArchiveFile failed: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read archive: %v", err)
+	}
+
+	want := "keep me\n-- z/b.txt --\nb\n-- a.txt --\nA\n-- d.txt --\nd\n"
+	if string(data) != want {
+		t.Errorf("Unexpected archive:\n%s", data)
+	}
+
+	entries, _ := os.ReadDir(filepath.Dir(path))
+	if len(entries) != 1 {
+		t.Errorf("Expected temp file to be cleaned up, found %d entries", len(entries))
+	}
+}
@@ -0,0 +1,272 @@
+package internal
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	"golang.org/x/tools/txtar"
+)
+
+type jsonFile struct {
+	Path    string `json:"path"`
+	Content string `json:"content"`
+}
+
+var languageByExt = map[string]string{
+	".go":    "go",
+	".mod":   "go",
+	".py":    "python",
+	".js":    "javascript",
+	".mjs":   "javascript",
+	".jsx":   "jsx",
+	".ts":    "typescript",
+	".tsx":   "tsx",
+	".rs":    "rust",
+	".c":     "c",
+	".h":     "c",
+	".cc":    "cpp",
+	".cpp":   "cpp",
+	".hpp":   "cpp",
+	".java":  "java",
+	".kt":    "kotlin",
+	".rb":    "ruby",
+	".php":   "php",
+	".cs":    "csharp",
+	".swift": "swift",
+	".sh":    "bash",
+	".bash":  "bash",
+	".zsh":   "zsh",
+	".ps1":   "powershell",
+	".sql":   "sql",
+	".html":  "html",
+	".css":   "css",
+	".scss":  "scss",
+	".json":  "json",
+	".yaml":  "yaml",
+	".yml":   "yaml",
+	".toml":  "toml",
+	".xml":   "xml",
+	".md":    "markdown",
+	".proto": "protobuf",
+	".tf":    "hcl",
+	".lua":   "lua",
+}
+
+func languageFor(name string) string {
+	base := path.Base(name)
+	switch base {
+	case "Makefile", "makefile", "GNUmakefile":
+		return "makefile"
+	case "Dockerfile":
+		return "dockerfile"
+	}
+	return languageByExt[strings.ToLower(path.Ext(base))]
+}
+
+// FormatArchiveAs serializes archive as txtar, markdown, json, or xml.
+func FormatArchiveAs(archive *txtar.Archive, format string) ([]byte, error) {
+	switch format {
+	case "", "txtar":
+		return txtar.Format(archive), nil
+	case "markdown", "md":
+		return formatMarkdown(archive), nil
+	case "json":
+		files := make([]jsonFile, 0, len(archive.Files))
+		for _, f := range archive.Files {
+			files = append(files, jsonFile{Path: f.Name, Content: string(f.Data)})
+		}
+		data, err := json.MarshalIndent(files, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		return append(data, '\n'), nil
+	case "xml":
+		return formatXML(archive), nil
+	default:
+		return nil, fmt.Errorf("unknown format %q (want txtar, markdown, json, or xml)", format)
+	}
+}
+
+func formatMarkdown(archive *txtar.Archive) []byte {
+	var buf bytes.Buffer
+	if len(archive.Comment) > 0 {
+		buf.Write(fixNL(archive.Comment))
+		buf.WriteByte('\n')
+	}
+
+	for i, f := range archive.Files {
+		if i > 0 {
+			buf.WriteByte('\n')
+		}
+		fence := markdownFence(f.Data)
+		fmt.Fprintf(&buf, "## `%s`\n\n", f.Name)
+		fmt.Fprintf(&buf, "%s%s\n", fence, languageFor(f.Name))
+		buf.Write(fixNL(f.Data))
+		fmt.Fprintf(&buf, "%s\n", fence)
+	}
+
+	return buf.Bytes()
+}
+
+// markdownFence returns a backtick fence longer than any run inside data.
+func markdownFence(data []byte) string {
+	longest, run := 0, 0
+	for _, b := range data {
+		if b == '`' {
+			run++
+			if run > longest {
+				longest = run
+			}
+		} else {
+			run = 0
+		}
+	}
+	return strings.Repeat("`", max(3, longest+1))
+}
+
+func formatXML(archive *txtar.Archive) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("<files>\n")
+	if len(archive.Comment) > 0 {
+		fmt.Fprintf(&buf, "<comment>%s</comment>\n", cdata(archive.Comment))
+	}
+	for _, f := range archive.Files {
+		buf.WriteString(`<file path="`)
+		xml.EscapeText(&buf, []byte(f.Name))
+		fmt.Fprintf(&buf, "\">%s</file>\n", cdata(f.Data))
+	}
+	buf.WriteString("</files>\n")
+	return buf.Bytes()
+}
+
+func cdata(data []byte) string {
+	// "]]>" cannot appear inside CDATA, so split the section around it.
+	return "<![CDATA[" + strings.ReplaceAll(string(data), "]]>", "]]]]><![CDATA[>") + "]]>"
+}
+
+// ParseArchiveAs parses data written in the given format. With "auto" the
+// format is guessed from the content.
+func ParseArchiveAs(data []byte, format string) (*txtar.Archive, error) {
+	if format == "auto" {
+		format = detectArchiveFormat(data)
+	}
+
+	switch format {
+	case "", "txtar":
+		return txtar.Parse(data), nil
+	case "markdown", "md":
+		return parseMarkdown(data)
+	case "json":
+		var files []jsonFile
+		if err := json.Unmarshal(data, &files); err != nil {
+			return nil, fmt.Errorf("invalid JSON archive: %w", err)
+		}
+		archive := &txtar.Archive{}
+		for _, f := range files {
+			if f.Path == "" {
+				return nil, fmt.Errorf("invalid JSON archive: entry without path")
+			}
+			archive.Files = append(archive.Files, txtar.File{Name: f.Path, Data: []byte(f.Content)})
+		}
+		return archive, nil
+	default:
+		return nil, fmt.Errorf("unknown input format %q (want txtar, markdown, json, or auto)", format)
+	}
+}
+
+func detectArchiveFormat(data []byte) string {
+	trimmed := bytes.TrimSpace(data)
+	if bytes.HasPrefix(trimmed, []byte("[")) {
+		return "json"
+	}
+	if len(txtar.Parse(data).Files) > 0 {
+		return "txtar"
+	}
+	if bytes.Contains(data, []byte("```")) || bytes.Contains(data, []byte("~~~")) {
+		return "markdown"
+	}
+	return "txtar"
+}
+
+var (
+	mdHeadingPath = regexp.MustCompile("^#{1,6}\\s+(?:File:\\s*)?`?([^`\\s]+)`?\\s*$")
+	mdBoldPath    = regexp.MustCompile("^\\*\\*`?([^`*\\s]+)`?\\*\\*:?\\s*$")
+	mdCodePath    = regexp.MustCompile("^`([^`\\s]+)`:?\\s*$")
+	mdFenceOpen   = regexp.MustCompile("^(`{3,}|~{3,})\\s*([^`]*)$")
+)
+
+func looksLikePath(s string) bool {
+	return strings.Contains(s, "/") || strings.Contains(s, ".")
+}
+
+// parseMarkdown extracts fenced code blocks that are labelled with a path,
+// either in a heading, bold or code-span line right before the block, or in
+// the fence info string ("```go path/to/file.go" or "```path/to/file.go").
+func parseMarkdown(data []byte) (*txtar.Archive, error) {
+	archive := &txtar.Archive{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+
+	var pending string
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), " \t\r")
+
+		if m := mdFenceOpen.FindStringSubmatch(line); m != nil {
+			fence := m[1]
+			name := pending
+			for _, field := range strings.Fields(m[2]) {
+				field = strings.TrimPrefix(field, "title=")
+				field = strings.Trim(field, `"'`)
+				if looksLikePath(field) {
+					name = field
+				}
+				if i := strings.Index(field, ":"); i > 0 && looksLikePath(field[i+1:]) {
+					name = field[i+1:]
+				}
+			}
+
+			var body bytes.Buffer
+			closed := false
+			for scanner.Scan() {
+				inner := scanner.Text()
+				trimmed := strings.TrimSpace(inner)
+				if strings.HasPrefix(trimmed, fence[:1]) && strings.Trim(trimmed, fence[:1]) == "" && len(trimmed) >= len(fence) {
+					closed = true
+					break
+				}
+				body.WriteString(strings.TrimSuffix(inner, "\r"))
+				body.WriteByte('\n')
+			}
+			if !closed {
+				return nil, fmt.Errorf("unterminated code block for %q", name)
+			}
+
+			if name != "" {
+				archive.Files = append(archive.Files, txtar.File{Name: name, Data: body.Bytes()})
+			}
+			pending = ""
+			continue
+		}
+
+		for _, re := range []*regexp.Regexp{mdHeadingPath, mdBoldPath, mdCodePath} {
+			if m := re.FindStringSubmatch(line); m != nil && looksLikePath(m[1]) {
+				pending = m[1]
+				break
+			}
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(archive.Files) == 0 {
+		return nil, fmt.Errorf("no labelled code blocks found in markdown")
+	}
+	return archive, nil
+}
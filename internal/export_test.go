@@ -0,0 +1,86 @@
+package internal
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+
+	"golang.org/x/tools/txtar"
+)
+
+var exportArchive = &txtar.Archive{
+	Comment: []byte("context\n"),
+	Files: []txtar.File{
+		{Name: "main.go", Data: []byte("package main\n")},
+		{Name: "docs/README.md", Data: []byte("```sh\nmake\n```\n")},
+		{Name: "odd.txt", Data: []byte("a ]]> b\n")},
+	},
+}
+
+func TestMarkdownRoundTrip(t *testing.T) {
+	data, err := FormatArchiveAs(exportArchive, "markdown")
+	if err != nil {
+		t.Fatalf("FormatArchiveAs failed: %v", err)
+	}
+
+	if !strings.Contains(string(data), "## `main.go`\n\n```go\npackage main\n```\n") {
+		t.Errorf("Unexpected markdown:\n%s", data)
+	}
+	if !strings.Contains(string(data), "````markdown\n") {
+		t.Errorf("Expected longer fence around nested backticks:\n%s", data)
+	}
+
+	archive, err := ParseArchiveAs(data, "auto")
+	if err != nil {
+		t.Fatalf("ParseArchiveAs failed: %v", err)
+	}
+	if len(archive.Files) != 3 || string(archive.Files[1].Data) != string(exportArchive.Files[1].Data) {
+		t.Errorf("Markdown round trip lost data: %+v", archive.Files)
+	}
+}
+
+func TestParseMarkdownReplyStyles(t *testing.T) {
+	reply := "Sure! Here are the changes.\n\n" +
+		"**cmd/root.go**\n```go\npackage cmd\n```\n\n" +
+		"```python scripts/gen.py\nprint(1)\n```\n\n" +
+		"```go\n// unlabelled, ignored\n```\n"
+
+	archive, err := ParseArchiveAs([]byte(reply), "markdown")
+	if err != nil {
+		t.Fatalf("ParseArchiveAs failed: %v", err)
+	}
+
+	if len(archive.Files) != 2 || archive.Files[0].Name != "cmd/root.go" || archive.Files[1].Name != "scripts/gen.py" {
+		t.Errorf("Unexpected files: %+v", archive.Files)
+	}
+}
+
+func TestJSONAndXML(t *testing.T) {
+	data, err := FormatArchiveAs(exportArchive, "json")
+	if err != nil {
+		t.Fatalf("FormatArchiveAs json failed: %v", err)
+	}
+
+	archive, err := ParseArchiveAs(data, "auto")
+	if err != nil || len(archive.Files) != 3 || archive.Files[2].Name != "odd.txt" {
+		t.Errorf("JSON round trip failed: %+v, %v", archive, err)
+	}
+
+	data, err = FormatArchiveAs(exportArchive, "xml")
+	if err != nil {
+		t.Fatalf("FormatArchiveAs xml failed: %v", err)
+	}
+
+	var parsed struct {
+		Files []struct {
+			Path    string `xml:"path,attr"`
+			Content string `xml:",chardata"`
+		} `xml:"file"`
+	}
+	if err := xml.Unmarshal(data, &parsed); err != nil {
+		t.Fatalf("Generated XML is invalid: %v\n%s", err, data)
+	}
+	if len(parsed.Files) != 3 || parsed.Files[2].Content != "a ]]> b\n" {
+		t.Errorf("Unexpected XML content: %+v", parsed.Files)
+	}
+}
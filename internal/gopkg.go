@@ -0,0 +1,333 @@
+package internal
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/tools/go/ast/astutil"
+	"golang.org/x/tools/go/packages"
+)
+
+// goPackageFiles loads the packages matching patterns with the go command
+// and returns the source files of those packages and of every package they
+// import from the main module, relative to dir, including files excluded by
+// build constraints. The module's go.mod is included when it is under dir.
+func goPackageFiles(dir string, patterns []string, withTests bool) ([]string, error) {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := &packages.Config{
+		Mode:  packages.NeedName | packages.NeedFiles | packages.NeedImports | packages.NeedDeps | packages.NeedModule | packages.NeedEmbedFiles,
+		Dir:   absDir,
+		Tests: withTests,
+	}
+	pkgs, err := packages.Load(cfg, patterns...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load Go packages: %w", err)
+	}
+	if len(pkgs) == 0 {
+		return nil, fmt.Errorf("no Go packages match %s", strings.Join(patterns, " "))
+	}
+
+	var loadErrs []string
+	seen := make(map[string]bool)
+	packages.Visit(pkgs, nil, func(p *packages.Package) {
+		for _, e := range p.Errors {
+			loadErrs = append(loadErrs, e.Error())
+		}
+		// Skip dependencies outside the main module and the generated
+		// test main packages.
+		if p.Module == nil || !p.Module.Main || strings.HasSuffix(p.ID, ".test") {
+			return
+		}
+
+		// IgnoredFiles holds files excluded by build constraints, such as
+		// _windows.go files on Linux; they are part of the source too.
+		for _, list := range [][]string{p.GoFiles, p.OtherFiles, p.EmbedFiles, p.IgnoredFiles} {
+			for _, f := range list {
+				seen[f] = true
+			}
+		}
+		if p.Module.GoMod != "" {
+			seen[p.Module.GoMod] = true
+		}
+	})
+	if len(loadErrs) > 0 {
+		return nil, fmt.Errorf("failed to load Go packages: %s", strings.Join(loadErrs, "; "))
+	}
+
+	var files []string
+	for abs := range seen {
+		rel, err := filepath.Rel(absDir, abs)
+		if err != nil {
+			return nil, err
+		}
+		if rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			if filepath.Base(abs) == "go.mod" {
+				continue
+			}
+			return nil, fmt.Errorf("%s is outside %s; pack from the module root", abs, dir)
+		}
+		files = append(files, rel)
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+func packGoPackages(opts PackOptions, filter *Filter) ([]string, map[string][]byte, error) {
+	dir := opts.Dir
+	if dir == "" {
+		dir = "."
+	}
+
+	all, err := goPackageFiles(dir, opts.GoPackages, opts.WithTests)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var files []string
+	fileContents := make(map[string][]byte)
+	for _, f := range all {
+		if !filter.ShouldInclude(f) {
+			continue
+		}
+
+		content, err := os.ReadFile(filepath.Join(dir, f))
+		if err != nil {
+			return nil, nil, err
+		}
+		if filter.ignoreBinary && isBinaryContent(content) {
+			continue
+		}
+
+		files = append(files, f)
+		fileContents[f] = content
+	}
+
+	return files, fileContents, nil
+}
+
+// stubBody replaces a function body with a call to panic, which keeps the
+// file valid Go whatever the function returns.
+func stubBody(body *ast.BlockStmt) *ast.BlockStmt {
+	return &ast.BlockStmt{
+		Lbrace: body.Lbrace,
+		List: []ast.Stmt{&ast.ExprStmt{X: &ast.CallExpr{
+			Fun:  ast.NewIdent("panic"),
+			Args: []ast.Expr{&ast.BasicLit{Kind: token.STRING, Value: strconv.Quote("stub")}},
+		}}},
+		Rbrace: body.Lbrace + 1,
+	}
+}
+
+// receiverExported reports whether a method's receiver type is exported.
+func receiverExported(recv *ast.FieldList) bool {
+	if recv == nil || len(recv.List) == 0 {
+		return true
+	}
+
+	t := recv.List[0].Type
+	for {
+		switch x := t.(type) {
+		case *ast.StarExpr:
+			t = x.X
+		case *ast.IndexExpr:
+			t = x.X
+		case *ast.IndexListExpr:
+			t = x.X
+		case *ast.ParenExpr:
+			t = x.X
+		case *ast.Ident:
+			return x.IsExported()
+		default:
+			return false
+		}
+	}
+}
+
+// exportedSpec reports whether a type, const or var spec declares an
+// exported name.
+func exportedSpec(spec ast.Spec) bool {
+	switch s := spec.(type) {
+	case *ast.TypeSpec:
+		return s.Name.IsExported()
+	case *ast.ValueSpec:
+		for _, n := range s.Names {
+			if n.IsExported() {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// exportedGroup reports whether any spec in d declares an exported name.
+func exportedGroup(d *ast.GenDecl) bool {
+	for _, spec := range d.Specs {
+		if exportedSpec(spec) {
+			return true
+		}
+	}
+	return false
+}
+
+// implicitRepetition reports whether a const spec in d repeats the previous
+// spec's type and values by omitting its own.
+func implicitRepetition(d *ast.GenDecl) bool {
+	for _, spec := range d.Specs {
+		if s, ok := spec.(*ast.ValueSpec); ok && s.Type == nil && len(s.Values) == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+type posRange struct{ start, end token.Pos }
+
+// printGoFile prints file without the comments inside any of the dropped
+// ranges.
+func printGoFile(fset *token.FileSet, file *ast.File, dropped []posRange) ([]byte, error) {
+	var comments []*ast.CommentGroup
+	for _, g := range file.Comments {
+		keep := true
+		for _, r := range dropped {
+			if g.Pos() >= r.start && g.End() <= r.end {
+				keep = false
+				break
+			}
+		}
+		if keep {
+			comments = append(comments, g)
+		}
+	}
+	file.Comments = comments
+
+	var buf bytes.Buffer
+	if err := (&printer.Config{Mode: printer.UseSpaces | printer.TabIndent, Tabwidth: 8}).Fprint(&buf, fset, file); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// pruneImports deletes imports that file no longer uses. Blank, dot and
+// cgo imports are kept.
+func pruneImports(fset *token.FileSet, file *ast.File) {
+	for _, imp := range append([]*ast.ImportSpec(nil), file.Imports...) {
+		if imp.Name != nil && (imp.Name.Name == "_" || imp.Name.Name == ".") {
+			continue
+		}
+		path, err := strconv.Unquote(imp.Path.Value)
+		if err != nil || path == "C" || astutil.UsesImport(file, path) {
+			continue
+		}
+		name := ""
+		if imp.Name != nil {
+			name = imp.Name.Name
+		}
+		astutil.DeleteNamedImport(fset, file, name, path)
+	}
+}
+
+func declRange(decl ast.Decl, doc *ast.CommentGroup) posRange {
+	r := posRange{decl.Pos(), decl.End()}
+	if doc != nil {
+		r.start = doc.Pos()
+	}
+	return r
+}
+
+// goExportsOnly keeps the exported declarations of a Go file and stubs out
+// the bodies of its exported functions and methods.
+func goExportsOnly(name string, src []byte) ([]byte, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, name, src, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+
+	var dropped []posRange
+	var decls []ast.Decl
+	for _, decl := range file.Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			if !d.Name.IsExported() || !receiverExported(d.Recv) {
+				dropped = append(dropped, declRange(d, d.Doc))
+				continue
+			}
+			if d.Body != nil {
+				dropped = append(dropped, posRange{d.Body.Pos(), d.Body.End()})
+				d.Body = stubBody(d.Body)
+			}
+
+		case *ast.GenDecl:
+			if d.Tok == token.IMPORT {
+				break
+			}
+
+			// A const group using implicit repetition, as iota enums do, is
+			// kept whole: dropping its first spec would leave the rest with
+			// no type or value.
+			if d.Tok == token.CONST && exportedGroup(d) && implicitRepetition(d) {
+				break
+			}
+
+			var specs []ast.Spec
+			for _, spec := range d.Specs {
+				if exportedSpec(spec) {
+					specs = append(specs, spec)
+					continue
+				}
+				var doc *ast.CommentGroup
+				switch s := spec.(type) {
+				case *ast.TypeSpec:
+					doc = s.Doc
+				case *ast.ValueSpec:
+					doc = s.Doc
+				}
+				r := posRange{spec.Pos(), spec.End()}
+				if doc != nil {
+					r.start = doc.Pos()
+				}
+				dropped = append(dropped, r)
+			}
+			if len(specs) == 0 {
+				dropped = append(dropped, declRange(d, d.Doc))
+				continue
+			}
+			d.Specs = specs
+		}
+		decls = append(decls, decl)
+	}
+	file.Decls = decls
+
+	pruneImports(fset, file)
+	return printGoFile(fset, file, dropped)
+}
+
+// applyExportsOnly rewrites every Go file to its exported API. Files that do
+// not parse are kept as they are.
+func applyExportsOnly(files []string, contents map[string][]byte) {
+	for _, f := range files {
+		if filepath.Ext(f) != ".go" {
+			continue
+		}
+
+		out, err := goExportsOnly(f, contents[f])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Kept (exports-only): %s: %v\n", f, err)
+			continue
+		}
+		contents[f] = out
+	}
+}
@@ -0,0 +1,137 @@
+package internal
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeModule(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestPackGoPackages(t *testing.T) {
+	dir := writeModule(t, map[string]string{
+		"go.mod":          "module example.com/m\n\ngo 1.21\n",
+		"a/a.go":          "package a\n\nimport \"example.com/m/b\"\n\nfunc A() string { return b.B() }\n",
+		"a/a_test.go":     "package a\n\nimport \"testing\"\n\nfunc TestA(t *testing.T) {}\n",
+		"a/a_plan9.go":    "//go:build plan9\n\npackage a\n",
+		"b/b.go":          "package b\n\nimport \"strings\"\n\nfunc B() string { return strings.ToUpper(\"b\") }\n",
+		"c/c.go":          "package c\n",
+		"a/testdata/x.md": "not Go\n",
+	})
+
+	_, files, err := Pack(context.Background(), PackOptions{Dir: dir, GoPackages: []string{"./a"}, DryRun: true})
+	if err != nil {
+		t.Fatalf("Pack failed: %v", err)
+	}
+	if got := strings.Join(files, " "); got != "a/a.go a/a_plan9.go b/b.go go.mod" {
+		t.Errorf("Unexpected files: %s", got)
+	}
+
+	_, files, err = Pack(context.Background(), PackOptions{Dir: dir, GoPackages: []string{"./a"}, WithTests: true, DryRun: true})
+	if err != nil {
+		t.Fatalf("Pack failed: %v", err)
+	}
+	if got := strings.Join(files, " "); got != "a/a.go a/a_plan9.go a/a_test.go b/b.go go.mod" {
+		t.Errorf("Unexpected files with tests: %s", got)
+	}
+
+	if _, _, err := Pack(context.Background(), PackOptions{Dir: dir, GoPackages: []string{"./missing"}, DryRun: true}); err == nil {
+		t.Error("Expected error for a missing package")
+	}
+}
+
+func TestGoExportsOnly(t *testing.T) {
+	src := `package p
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Exported is kept.
+type Exported struct{ n int }
+
+// hidden is dropped.
+type hidden int
+
+const (
+	Max = 10
+	min = 1
+)
+
+// String formats e.
+func (e *Exported) String() string {
+	// inside the body
+	return fmt.Sprint(e.n)
+}
+
+func (h hidden) String() string { return "" }
+
+func helper() string { return strings.ToUpper("x") }
+`
+	out, err := goExportsOnly("p.go", []byte(src))
+	if err != nil {
+		t.Fatalf("goExportsOnly failed: %v", err)
+	}
+	got := string(out)
+
+	for _, want := range []string{"// Exported is kept.", "type Exported struct", "Max = 10", "// String formats e.", `func (e *Exported) String() string { panic("stub") }`} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Missing %q in:\n%s", want, got)
+		}
+	}
+	for _, gone := range []string{"hidden", "min = 1", "helper", "inside the body", "strings", "fmt"} {
+		if strings.Contains(got, gone) {
+			t.Errorf("Unexpected %q in:\n%s", gone, got)
+		}
+	}
+
+	if _, err := goExportsOnly("bad.go", []byte("package p\nfunc {")); err == nil {
+		t.Error("Expected parse error")
+	}
+}
+
+func TestGoExportsOnlyKeepsIotaGroups(t *testing.T) {
+	src := `package p
+
+type Kind int
+
+const (
+	kindNone Kind = iota
+	KindA
+	KindB
+)
+
+const (
+	Max   = 10
+	limit = 3
+)
+`
+	out, err := goExportsOnly("p.go", []byte(src))
+	if err != nil {
+		t.Fatalf("goExportsOnly failed: %v", err)
+	}
+	got := string(out)
+
+	if !strings.Contains(got, "kindNone Kind = iota") || !strings.Contains(got, "KindB") {
+		t.Errorf("iota group not kept whole:\n%s", got)
+	}
+	if strings.Contains(got, "limit") || !strings.Contains(got, "Max") {
+		t.Errorf("Explicit const group not filtered:\n%s", got)
+	}
+}
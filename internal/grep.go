@@ -0,0 +1,130 @@
+package internal
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"regexp"
+)
+
+type GrepOptions struct {
+	Pattern          string
+	IgnoreCase       bool
+	LineNumbers      bool
+	FilesWithMatches bool
+	Context          int
+	Include          []string
+	Exclude          []string
+	Label            string
+}
+
+// Grep searches every entry read from r and writes matching lines to w.
+// It returns the number of matching lines (or files, with FilesWithMatches).
+func Grep(r io.Reader, w io.Writer, opts GrepOptions) (int, error) {
+	pattern := opts.Pattern
+	if opts.IgnoreCase {
+		pattern = "(?i)" + pattern
+	}
+
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return 0, fmt.Errorf("invalid pattern: %w", err)
+	}
+
+	filter := &Filter{
+		include: opts.Include,
+		exclude: opts.Exclude,
+	}
+
+	reader := NewReader(r)
+	matches := 0
+	printed := false
+
+	for {
+		file, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return matches, err
+		}
+
+		if !filter.ShouldInclude(file.Name) {
+			continue
+		}
+
+		lines := splitLines(file.Data)
+		var hits []int
+		for i, line := range lines {
+			if re.Match(line) {
+				hits = append(hits, i)
+			}
+		}
+
+		if len(hits) == 0 {
+			continue
+		}
+
+		if opts.FilesWithMatches {
+			matches++
+			fmt.Fprintf(w, "%s%s\n", grepLabel(opts.Label), file.Name)
+			continue
+		}
+
+		matches += len(hits)
+		if printed && opts.Context > 0 {
+			fmt.Fprintln(w, "--")
+		}
+		printed = true
+		printGrepHits(w, opts, file.Name, lines, hits)
+	}
+
+	return matches, nil
+}
+
+func printGrepHits(w io.Writer, opts GrepOptions, name string, lines [][]byte, hits []int) {
+	isHit := make(map[int]bool, len(hits))
+	for _, h := range hits {
+		isHit[h] = true
+	}
+
+	last := -1
+	for _, h := range hits {
+		start := max(h-opts.Context, last+1)
+		end := min(h+opts.Context, len(lines)-1)
+		if last >= 0 && start > last+1 {
+			fmt.Fprintln(w, "--")
+		}
+
+		for i := start; i <= end; i++ {
+			sep := "-"
+			if isHit[i] {
+				sep = ":"
+			}
+			fmt.Fprint(w, grepLabel(opts.Label), name, sep)
+			if opts.LineNumbers {
+				fmt.Fprint(w, i+1, sep)
+			}
+			fmt.Fprintf(w, "%s\n", lines[i])
+		}
+
+		if end > last {
+			last = end
+		}
+	}
+}
+
+func grepLabel(label string) string {
+	if label == "" {
+		return ""
+	}
+	return label + ":"
+}
+
+func splitLines(data []byte) [][]byte {
+	data = bytes.TrimSuffix(data, []byte("\n"))
+	if len(data) == 0 {
+		return nil
+	}
+	return bytes.Split(data, []byte("\n"))
+}
@@ -0,0 +1,95 @@
+package internal
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+)
+
+const grepArchive = `comment
+-- a.go --
+package a
+
+func Foo() {}
+-- b/b.go --
+package b
+// calls foo
+func Bar() { Foo() }
+-- b/b.txt --
+nothing here
+`
+
+func TestReaderMatchesParse(t *testing.T) {
+	r := NewReader(strings.NewReader(grepArchive))
+
+	var names []string
+	for {
+		f, err := r.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Next failed: %v", err)
+		}
+		names = append(names, f.Name)
+	}
+
+	if string(r.Comment()) != "comment\n" {
+		t.Errorf("Expected comment, got %q", r.Comment())
+	}
+
+	if strings.Join(names, ",") != "a.go,b/b.go,b/b.txt" {
+		t.Errorf("Unexpected entries: %v", names)
+	}
+}
+
+func TestGrep(t *testing.T) {
+	var out bytes.Buffer
+	n, err := Grep(strings.NewReader(grepArchive), &out, GrepOptions{
+		Pattern:     "foo",
+		IgnoreCase:  true,
+		LineNumbers: true,
+		Include:     []string{"**/*.go"},
+	})
+	if err != nil {
+		t.Fatalf("Grep failed: %v", err)
+	}
+
+	if n != 3 {
+		t.Errorf("Expected 3 matches, got %d", n)
+	}
+
+	want := "a.go:3:func Foo() {}\nb/b.go:2:// calls foo\nb/b.go:3:func Bar() { Foo() }\n"
+	if out.String() != want {
+		t.Errorf("Unexpected output:\n%s", out.String())
+	}
+}
+
+func TestGrepContextAndFiles(t *testing.T) {
+	var out bytes.Buffer
+	_, err := Grep(strings.NewReader(grepArchive), &out, GrepOptions{
+		Pattern: "Bar",
+		Context: 1,
+	})
+	if err != nil {
+		t.Fatalf("Grep failed: %v", err)
+	}
+
+	if out.String() != "b/b.go-// calls foo\nb/b.go:func Bar() { Foo() }\n" {
+		t.Errorf("Unexpected context output:\n%s", out.String())
+	}
+
+	out.Reset()
+	n, err := Grep(strings.NewReader(grepArchive), &out, GrepOptions{
+		Pattern:          "package",
+		FilesWithMatches: true,
+	})
+	if err != nil {
+		t.Fatalf("Grep failed: %v", err)
+	}
+
+	if n != 2 || out.String() != "a.go\nb/b.go\n" {
+		t.Errorf("Unexpected -l output (%d):\n%s", n, out.String())
+	}
+}
@@ -0,0 +1,57 @@
+package internal
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// RunHooks runs each shell command in dir. The affected files are passed
+// one per line on stdin and in a temporary file named by TXTAR_FILE_LIST;
+// TXTAR_HOOK names the stage. The list is not put in the environment, which
+// is too small for large archives.
+// Hook output goes to stderr so it never mixes with an archive on stdout.
+// The first failing command stops the run and is returned as an error.
+func RunHooks(stage string, commands []string, dir string, files []string, env ...string) error {
+	list := strings.Join(files, "\n")
+	if list != "" {
+		list += "\n"
+	}
+
+	listFile, err := os.CreateTemp("", "txtar-files-*")
+	if err != nil {
+		return fmt.Errorf("%s hook: %w", stage, err)
+	}
+	defer os.Remove(listFile.Name())
+
+	_, err = listFile.WriteString(list)
+	if cerr := listFile.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return fmt.Errorf("%s hook: %w", stage, err)
+	}
+
+	for _, command := range commands {
+		var cmd *exec.Cmd
+		if runtime.GOOS == "windows" {
+			cmd = exec.Command("cmd", "/C", command)
+		} else {
+			cmd = exec.Command("sh", "-c", command)
+		}
+
+		cmd.Dir = dir
+		cmd.Env = append(os.Environ(), "TXTAR_HOOK="+stage, "TXTAR_FILE_LIST="+listFile.Name())
+		cmd.Env = append(cmd.Env, env...)
+		cmd.Stdin = strings.NewReader(list)
+		cmd.Stdout = os.Stderr
+		cmd.Stderr = os.Stderr
+
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("%s hook %q failed: %w", stage, command, err)
+		}
+	}
+	return nil
+}
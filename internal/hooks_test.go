@@ -0,0 +1,127 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"golang.org/x/tools/txtar"
+)
+
+func skipWithoutShell(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("hook tests use sh")
+	}
+}
+
+func TestRunHooksReceivesFiles(t *testing.T) {
+	skipWithoutShell(t)
+	dir := t.TempDir()
+
+	err := RunHooks("test", []string{`cat > stdin.txt; cat "$TXTAR_FILE_LIST" > list.txt`}, dir, []string{"a.go", "b/c.go"})
+	if err != nil {
+		t.Fatalf("RunHooks failed: %v", err)
+	}
+
+	for _, name := range []string{"stdin.txt", "list.txt"} {
+		data, _ := os.ReadFile(filepath.Join(dir, name))
+		if string(data) != "a.go\nb/c.go\n" {
+			t.Errorf("%s = %q", name, data)
+		}
+	}
+
+	if err := RunHooks("test", []string{"exit 3"}, dir, nil); err == nil {
+		t.Error("Expected error from failing hook")
+	}
+}
+
+func TestRunHooksManyFiles(t *testing.T) {
+	skipWithoutShell(t)
+	dir := t.TempDir()
+
+	files := make([]string, 5000)
+	for i := range files {
+		files[i] = fmt.Sprintf("some/fairly/deep/directory/tree/file-%04d.go", i)
+	}
+
+	if err := RunHooks("test", []string{`wc -l < "$TXTAR_FILE_LIST" > count.txt`}, dir, files); err != nil {
+		t.Fatalf("RunHooks failed: %v", err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "count.txt")); strings.TrimSpace(string(data)) != "5000" {
+		t.Errorf("Unexpected file count: %q", data)
+	}
+}
+
+func TestUnpackPostHookRollback(t *testing.T) {
+	skipWithoutShell(t)
+	tmpDir := t.TempDir()
+	existing := filepath.Join(tmpDir, "existing.txt")
+	os.WriteFile(existing, []byte("original\n"), 0644)
+
+	archive := &txtar.Archive{
+		Files: []txtar.File{
+			{Name: "existing.txt", Data: []byte("changed\n")},
+			{Name: "new/dir/file.txt", Data: []byte("new\n")},
+		},
+	}
+
+	opts := UnpackOptions{Dir: tmpDir, PostHooks: []string{"exit 1"}}
+	if err := Unpack(archive, opts); err == nil {
+		t.Fatal("Expected hook failure")
+	}
+
+	if data, _ := os.ReadFile(existing); string(data) != "original\n" {
+		t.Errorf("Overwritten file not restored: %q", data)
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, "new")); err == nil {
+		t.Error("Created directories not removed")
+	}
+
+	opts.PostHooks = []string{"grep -qx existing.txt"}
+	if err := Unpack(archive, opts); err != nil {
+		t.Fatalf("Unpack with passing hook failed: %v", err)
+	}
+	if data, _ := os.ReadFile(existing); string(data) != "changed\n" {
+		t.Errorf("File not written: %q", data)
+	}
+}
+
+func TestPackPreHookAborts(t *testing.T) {
+	skipWithoutShell(t)
+	tmpDir := t.TempDir()
+	os.WriteFile(filepath.Join(tmpDir, "secret.env"), []byte("TOKEN=x\n"), 0644)
+
+	opts := PackOptions{Dir: tmpDir, PreHooks: []string{"! test -f secret.env"}}
+	_, _, err := Pack(context.Background(), opts)
+	if err == nil || !strings.Contains(err.Error(), "pack.pre") {
+		t.Errorf("Expected pack.pre failure, got %v", err)
+	}
+}
+
+func TestPackPreHookRunsBeforeCollection(t *testing.T) {
+	skipWithoutShell(t)
+	tmpDir := t.TempDir()
+	os.WriteFile(filepath.Join(tmpDir, "main.go"), []byte("package main\n"), 0644)
+
+	opts := PackOptions{Dir: tmpDir, PreHooks: []string{"echo generated > gen.txt"}, DryRun: true}
+	_, files, err := Pack(context.Background(), opts)
+	if err != nil {
+		t.Fatalf("Pack failed: %v", err)
+	}
+	if got := strings.Join(files, " "); got != "main.go" {
+		t.Errorf("Pre hook ran for a dry run: %s", got)
+	}
+
+	opts.DryRun = false
+	archive, _, err := Pack(context.Background(), opts)
+	if err != nil {
+		t.Fatalf("Pack failed: %v", err)
+	}
+	if len(archive.Files) != 2 || archive.Files[0].Name != "gen.txt" || string(archive.Files[0].Data) != "generated\n" {
+		t.Errorf("Generated file not packed: %+v", archive.Files)
+	}
+}
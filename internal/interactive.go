@@ -0,0 +1,249 @@
+package internal
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+const promptHelp = `y - write this change
+n - skip this change
+a - write this change and all remaining changes
+q - quit; skip this change and all remaining changes
+d - show the full diff for this file
+e - edit the proposed content before writing
+`
+
+// runEditor opens path in the user's editor. Tests replace it.
+var runEditor = func(path string, in io.Reader) error {
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+	}
+
+	args := append(strings.Fields(editor), path)
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stdin = in
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+type prompter struct {
+	in    io.Reader
+	lines *bufio.Reader
+	out   io.Writer
+	all   bool
+	quit  bool
+}
+
+func newPrompter(opts UnpackOptions) *prompter {
+	p := &prompter{in: opts.PromptIn, out: opts.PromptOut}
+	if p.in == nil {
+		p.in = os.Stdin
+	}
+	if p.out == nil {
+		p.out = os.Stderr
+	}
+	p.lines = bufio.NewReader(p.in)
+	return p
+}
+
+// ask prints prompt and returns the first letter of the answer. End of
+// input counts as quit.
+func (p *prompter) ask(prompt string) byte {
+	for {
+		fmt.Fprintf(p.out, "%s [y,n,a,q,d,e,?]? ", prompt)
+		line, err := p.lines.ReadString('\n')
+		answer := strings.ToLower(strings.TrimSpace(line))
+		if answer != "" && strings.ContainsRune("ynaqde", rune(answer[0])) {
+			return answer[0]
+		}
+		if err != nil {
+			fmt.Fprintln(p.out)
+			return 'q'
+		}
+		fmt.Fprint(p.out, promptHelp)
+	}
+}
+
+// edit lets the user change text in their editor and returns the result.
+func (p *prompter) edit(text, name string) (string, error) {
+	f, err := os.CreateTemp("", "txtar-edit-*"+filepath.Ext(name))
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(f.Name())
+
+	_, err = f.WriteString(text)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return "", err
+	}
+
+	if err := runEditor(f.Name(), p.in); err != nil {
+		return "", fmt.Errorf("editor failed: %w", err)
+	}
+
+	data, err := os.ReadFile(f.Name())
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// reviewActions asks the user about every file that would be created or
+// changed. Rejected files are marked skipped; with hunks, changed files are
+// reviewed hunk by hunk and the accepted hunks merged into the existing
+// content.
+func reviewActions(actions []unpackAction, opts UnpackOptions) error {
+	p := newPrompter(opts)
+
+	for i := range actions {
+		a := &actions[i]
+		if a.Status != actionCreate && a.Status != actionOverwrite {
+			continue
+		}
+
+		var err error
+		switch {
+		case p.quit:
+			a.Status = actionSkipped
+		case p.all:
+		case opts.Hunks && a.Status == actionOverwrite:
+			err = p.reviewHunks(a)
+		default:
+			err = p.reviewFile(a)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *prompter) reviewFile(a *unpackAction) error {
+	verb := "Overwrite"
+	if a.Status == actionCreate {
+		verb = "Create"
+	}
+
+	for {
+		switch p.ask(fmt.Sprintf("%s %s", verb, a.Target)) {
+		case 'y':
+			return nil
+		case 'n':
+			a.Status = actionSkipped
+			return nil
+		case 'a':
+			p.all = true
+			return nil
+		case 'q':
+			p.quit = true
+			a.Status = actionSkipped
+			return nil
+		case 'd':
+			fmt.Fprint(p.out, UnifiedDiff(a.Target, a.Target, a.Existing, a.Data))
+		case 'e':
+			edited, err := p.edit(string(a.Data), a.Target)
+			if err != nil {
+				return err
+			}
+			a.Data = []byte(edited)
+			return nil
+		}
+	}
+}
+
+func (p *prompter) reviewHunks(a *unpackAction) error {
+	lines := lineDiff(string(a.Existing), string(a.Data))
+	hunks := diffHunks(lines, 3)
+	outputs := make([]string, len(hunks))
+
+	for k, h := range hunks {
+		outputs[k] = hunkSide(h, '-')
+		if p.quit {
+			continue
+		}
+		if p.all {
+			outputs[k] = hunkSide(h, '+')
+			continue
+		}
+
+		writeHunk(p.out, h)
+		prompt := fmt.Sprintf("(%d/%d) Apply this hunk to %s", k+1, len(hunks), a.Target)
+
+	ask:
+		for {
+			switch p.ask(prompt) {
+			case 'y':
+				outputs[k] = hunkSide(h, '+')
+				break ask
+			case 'n':
+				break ask
+			case 'a':
+				p.all = true
+				outputs[k] = hunkSide(h, '+')
+				break ask
+			case 'q':
+				p.quit = true
+				break ask
+			case 'd':
+				fmt.Fprint(p.out, UnifiedDiff(a.Target, a.Target, a.Existing, a.Data))
+			case 'e':
+				edited, err := p.edit(hunkSide(h, '+'), a.Target)
+				if err != nil {
+					return err
+				}
+				outputs[k] = edited
+				break ask
+			}
+		}
+	}
+
+	merged := mergeHunks(lines, hunks, outputs)
+	if merged == string(a.Existing) {
+		a.Status = actionSkipped
+		return nil
+	}
+	a.Data = []byte(merged)
+	return nil
+}
+
+// hunkSide returns the old ('-') or new ('+') text covered by a hunk.
+func hunkSide(h diffHunk, side byte) string {
+	var b strings.Builder
+	for _, l := range h.Lines {
+		if l.Op == ' ' || l.Op == side {
+			b.WriteString(l.Text)
+		}
+	}
+	return b.String()
+}
+
+// mergeHunks rebuilds a file from an edit script, using outputs[k] in place
+// of the lines covered by hunks[k]. Lines outside hunks are unchanged.
+func mergeHunks(lines []diffLine, hunks []diffHunk, outputs []string) string {
+	var b strings.Builder
+	i := 0
+	for k, h := range hunks {
+		for ; i < h.Index; i++ {
+			b.WriteString(lines[i].Text)
+		}
+		b.WriteString(outputs[k])
+		i = h.Index + len(h.Lines)
+	}
+	for ; i < len(lines); i++ {
+		b.WriteString(lines[i].Text)
+	}
+	return b.String()
+}
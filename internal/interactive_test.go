@@ -0,0 +1,98 @@
+package internal
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/tools/txtar"
+)
+
+func TestUnpackInteractiveFiles(t *testing.T) {
+	tmpDir := t.TempDir()
+	os.WriteFile(filepath.Join(tmpDir, "keep.txt"), []byte("old\n"), 0644)
+
+	archive := &txtar.Archive{
+		Files: []txtar.File{
+			{Name: "keep.txt", Data: []byte("new\n")},
+			{Name: "add.txt", Data: []byte("added\n")},
+			{Name: "later.txt", Data: []byte("later\n")},
+		},
+	}
+
+	opts := UnpackOptions{
+		Dir:         tmpDir,
+		Interactive: true,
+		PromptIn:    strings.NewReader("x\nd\nn\ny\nq\n"),
+		PromptOut:   io.Discard,
+	}
+	if err := Unpack(archive, opts); err != nil {
+		t.Fatalf("Unpack failed: %v", err)
+	}
+
+	if data, _ := os.ReadFile(filepath.Join(tmpDir, "keep.txt")); string(data) != "old\n" {
+		t.Errorf("Rejected change was written: %q", data)
+	}
+	if data, _ := os.ReadFile(filepath.Join(tmpDir, "add.txt")); string(data) != "added\n" {
+		t.Errorf("Accepted file not written: %q", data)
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, "later.txt")); err == nil {
+		t.Error("File after quit was written")
+	}
+}
+
+func TestUnpackInteractiveHunks(t *testing.T) {
+	tmpDir := t.TempDir()
+	target := filepath.Join(tmpDir, "f.txt")
+	os.WriteFile(target, []byte("a\nb\nc\nd\ne\nf\ng\nh\ni\nj\n"), 0644)
+
+	archive := &txtar.Archive{
+		Files: []txtar.File{{Name: "f.txt", Data: []byte("A\nb\nc\nd\ne\nf\ng\nh\ni\nJ\n")}},
+	}
+
+	opts := UnpackOptions{
+		Dir:         tmpDir,
+		Interactive: true,
+		Hunks:       true,
+		PromptIn:    strings.NewReader("n\ny\n"),
+		PromptOut:   io.Discard,
+	}
+	if err := Unpack(archive, opts); err != nil {
+		t.Fatalf("Unpack failed: %v", err)
+	}
+
+	want := "a\nb\nc\nd\ne\nf\ng\nh\ni\nJ\n"
+	if data, _ := os.ReadFile(target); string(data) != want {
+		t.Errorf("Merged content = %q, want %q", data, want)
+	}
+}
+
+func TestUnpackInteractiveEdit(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	saved := runEditor
+	defer func() { runEditor = saved }()
+	runEditor = func(path string, in io.Reader) error {
+		return os.WriteFile(path, []byte("edited\n"), 0644)
+	}
+
+	archive := &txtar.Archive{
+		Files: []txtar.File{{Name: "f.txt", Data: []byte("proposed\n")}},
+	}
+
+	opts := UnpackOptions{
+		Dir:         tmpDir,
+		Interactive: true,
+		PromptIn:    strings.NewReader("e\n"),
+		PromptOut:   io.Discard,
+	}
+	if err := Unpack(archive, opts); err != nil {
+		t.Fatalf("Unpack failed: %v", err)
+	}
+
+	if data, _ := os.ReadFile(filepath.Join(tmpDir, "f.txt")); string(data) != "edited\n" {
+		t.Errorf("Edited content not written: %q", data)
+	}
+}
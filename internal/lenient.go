@@ -0,0 +1,124 @@
+package internal
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"golang.org/x/tools/txtar"
+)
+
+var (
+	// looseMarker accepts marker lines mangled by chat tools: extra or
+	// trailing whitespace and dashes turned into en or em dashes. The name
+	// must be set off by whitespace so that rules like "-----" and PEM
+	// armor lines stay content.
+	looseMarker = regexp.MustCompile(`^(?:--|—|–)[ \t]+(\S.*?)[ \t]+(?:--|—|–)[ \t]*$`)
+	fenceLine   = regexp.MustCompile("^(`{3,}|~{3,})[\\w.+-]*[ \t]*$")
+
+	smartQuotes = strings.NewReplacer("“", `"`, "”", `"`, "‘", "'", "’", "'")
+)
+
+// ParseLenient parses txtar data copied from chat tools or other mangled
+// sources. It returns the archive and a description of every fix applied.
+func ParseLenient(data []byte) (*txtar.Archive, []string, error) {
+	var fixes []string
+
+	if n := bytes.Count(data, []byte("\r\n")); n > 0 {
+		data = bytes.ReplaceAll(data, []byte("\r\n"), []byte("\n"))
+		fixes = append(fixes, fmt.Sprintf("converted %d CRLF line endings to LF", n))
+	}
+
+	lines := strings.SplitAfter(string(data), "\n")
+
+	firstMarker, lastMarker, quotes := -1, -1, 0
+	for i, line := range lines {
+		text := strings.TrimSuffix(line, "\n")
+		if markerName([]byte(text)) == "" {
+			name := looseMarkerName(text)
+			if name == "" {
+				continue
+			}
+
+			fixed := fmt.Sprintf("-- %s --", name)
+			fixes = append(fixes, fmt.Sprintf("line %d: normalized marker %q to %q", i+1, text, fixed))
+			lines[i] = fixed + "\n"
+		}
+
+		// Smart quotes are only fixed in file names; file contents may
+		// use them on purpose.
+		if n := countSmartQuotes(lines[i]); n > 0 {
+			lines[i] = smartQuotes.Replace(lines[i])
+			quotes += n
+		}
+
+		if firstMarker < 0 {
+			firstMarker = i
+		}
+		lastMarker = i
+	}
+
+	if firstMarker < 0 {
+		return nil, fixes, fmt.Errorf("no file markers found")
+	}
+
+	// A code fence opening before the first marker wraps the archive; drop
+	// it with any prose around it, along with its closing fence.
+	start, end := 0, len(lines)
+	for i := firstMarker - 1; i >= 0; i-- {
+		m := fenceLine.FindStringSubmatch(strings.TrimSuffix(lines[i], "\n"))
+		if m == nil {
+			continue
+		}
+
+		fence := m[1]
+		for j := len(lines) - 1; j > lastMarker; j-- {
+			text := strings.TrimSpace(lines[j])
+			if strings.Trim(text, fence[:1]) == "" && len(text) >= len(fence) {
+				end = j
+				break
+			}
+		}
+
+		start = i + 1
+		if i > 0 {
+			fixes = append(fixes, fmt.Sprintf("stripped %d lines of prose before the code fence", i))
+		}
+		fixes = append(fixes, fmt.Sprintf("removed code fence %q", strings.TrimSpace(lines[i])))
+		if end < len(lines) {
+			if trailing := len(lines) - end - 1; trailing > 0 {
+				fixes = append(fixes, fmt.Sprintf("stripped %d lines after the closing code fence", trailing))
+			}
+		}
+		break
+	}
+
+	if quotes > 0 {
+		fixes = append(fixes, fmt.Sprintf("replaced %d smart quotes in file names with ASCII quotes", quotes))
+	}
+
+	return txtar.Parse([]byte(strings.Join(lines[start:end], ""))), fixes, nil
+}
+
+// looseMarkerName returns the file name of a mangled marker line, or "" if
+// text is not one. Only names that look like paths are accepted, so prose
+// such as "— Chapter one —" and dash rules stay content.
+func looseMarkerName(text string) string {
+	m := looseMarker.FindStringSubmatch(text)
+	if m == nil || strings.ContainsAny(m[1], " \t") || !looksLikePath(m[1]) || strings.Trim(m[1], "-—–.") == "" {
+		return ""
+	}
+	return m[1]
+}
+
+func countSmartQuotes(s string) int {
+	n := 0
+	for _, r := range s {
+		switch r {
+		case '“', '”', '‘', '’':
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,178 @@
+package internal
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"unicode/utf8"
+)
+
+// limitsSet reports whether any per-file or total size limit is configured.
+func limitsSet(opts PackOptions) bool {
+	return opts.MaxFileSize > 0 || opts.MaxTotalSize > 0 || opts.MaxLines > 0
+}
+
+// truncateLines keeps the first n lines of data and appends a marker.
+func truncateLines(data []byte, n int) []byte {
+	lines := bytes.SplitAfter(data, []byte("\n"))
+	if len(lines) > 0 && len(lines[len(lines)-1]) == 0 {
+		lines = lines[:len(lines)-1]
+	}
+	if len(lines) <= n {
+		return data
+	}
+
+	out := fixNL(bytes.Join(lines[:n], nil))
+	return append(out, fmt.Sprintf("... truncated %d lines ...\n", len(lines)-n)...)
+}
+
+// truncateBytes cuts data to at most size bytes, at the last line break if
+// there is one, and appends a marker.
+func truncateBytes(data []byte, size int64) []byte {
+	if int64(len(data)) <= size {
+		return data
+	}
+
+	kept := data[:size]
+	if i := bytes.LastIndexByte(kept, '\n'); i >= 0 {
+		kept = kept[:i+1]
+		dropped := bytes.Count(data[len(kept):], []byte("\n"))
+		if !bytes.HasSuffix(data, []byte("\n")) {
+			dropped++
+		}
+		return append(append([]byte(nil), kept...), fmt.Sprintf("... truncated %d lines ...\n", dropped)...)
+	}
+
+	// A single long line, such as a minified bundle: cut on a rune boundary.
+	for len(kept) > 0 && !utf8.Valid(kept) {
+		kept = kept[:len(kept)-1]
+	}
+	out := append(append([]byte(nil), kept...), '\n')
+	return append(out, fmt.Sprintf("... truncated %d bytes ...\n", len(data)-len(kept))...)
+}
+
+// limitPolicy resolves a per-limit policy, falling back to the shared one.
+func limitPolicy(policy, fallback string) (string, error) {
+	if policy == "" {
+		policy = fallback
+	}
+	switch policy {
+	case "":
+		return "skip", nil
+	case "skip", "truncate", "fail":
+		return policy, nil
+	}
+	return "", fmt.Errorf("unknown limit policy %q (want skip, truncate, or fail)", policy)
+}
+
+// applySizeLimits enforces MaxLines, MaxFileSize and MaxTotalSize, each with
+// its own policy or opts.LimitPolicy, reporting each affected file on
+// stderr. Under MaxTotalSize, files are kept in --priority order.
+func applySizeLimits(files []string, contents map[string][]byte, opts PackOptions) ([]string, error) {
+	policies := make(map[string]string)
+	for _, l := range []struct{ limit, policy string }{
+		{"max-lines", opts.LinesPolicy},
+		{"max-file-size", opts.FileSizePolicy},
+		{"max-total-size", opts.TotalSizePolicy},
+	} {
+		policy, err := limitPolicy(l.policy, opts.LimitPolicy)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", l.limit, err)
+		}
+		policies[l.limit] = policy
+	}
+
+	skipped := make(map[string]bool)
+	affected := 0
+	apply := func(file, limit, detail string, truncate func([]byte) []byte) error {
+		affected++
+		policy := policies[limit]
+		switch {
+		case policy == "fail":
+			return fmt.Errorf("%s exceeds %s (%s)", file, limit, detail)
+		case policy == "truncate" && !isBinaryContent(contents[file]) && truncate != nil:
+			contents[file] = truncate(contents[file])
+			fmt.Fprintf(os.Stderr, "Truncated (%s): %s (%s)\n", limit, file, detail)
+		default:
+			skipped[file] = true
+			fmt.Fprintf(os.Stderr, "Skipped (%s): %s (%s)\n", limit, file, detail)
+		}
+		return nil
+	}
+
+	for _, f := range files {
+		data := contents[f]
+
+		if opts.MaxLines > 0 {
+			if n := countLines(data); n > opts.MaxLines {
+				err := apply(f, "max-lines", fmt.Sprintf("%d lines", n), func(d []byte) []byte {
+					return truncateLines(d, opts.MaxLines)
+				})
+				if err != nil {
+					return nil, err
+				}
+			}
+		}
+
+		if !skipped[f] && opts.MaxFileSize > 0 && int64(len(contents[f])) > opts.MaxFileSize {
+			err := apply(f, "max-file-size", fmt.Sprintf("%d bytes", len(contents[f])), func(d []byte) []byte {
+				return truncateBytes(d, opts.MaxFileSize)
+			})
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if opts.MaxTotalSize > 0 {
+		ordered := append([]string(nil), files...)
+		sort.SliceStable(ordered, func(i, j int) bool {
+			return priorityRank(filepath.ToSlash(ordered[i]), opts.Priority) < priorityRank(filepath.ToSlash(ordered[j]), opts.Priority)
+		})
+
+		remaining := opts.MaxTotalSize
+		for _, f := range ordered {
+			if skipped[f] {
+				continue
+			}
+
+			size := int64(len(contents[f]))
+			if size <= remaining {
+				remaining -= size
+				continue
+			}
+
+			// With nothing left to fill, truncating would only leave a marker.
+			var truncate func([]byte) []byte
+			if remaining > 0 {
+				truncate = func(d []byte) []byte {
+					return truncateBytes(d, remaining)
+				}
+			}
+
+			err := apply(f, "max-total-size", fmt.Sprintf("%d bytes, %d left", size, remaining), truncate)
+			if err != nil {
+				return nil, err
+			}
+			if !skipped[f] {
+				remaining = 0
+			}
+		}
+	}
+
+	if affected == 0 {
+		return files, nil
+	}
+
+	var kept []string
+	for _, f := range files {
+		if !skipped[f] {
+			kept = append(kept, f)
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "Size limits: %d files affected, %d skipped\n", affected, len(skipped))
+	return kept, nil
+}
@@ -0,0 +1,116 @@
+package internal
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTruncateLines(t *testing.T) {
+	got := string(truncateLines([]byte("1\n2\n3\n4\n5\n"), 2))
+	if got != "1\n2\n... truncated 3 lines ...\n" {
+		t.Errorf("Unexpected truncation: %q", got)
+	}
+
+	if got := string(truncateLines([]byte("1\n2\n"), 2)); got != "1\n2\n" {
+		t.Errorf("Short file changed: %q", got)
+	}
+}
+
+func TestTruncateBytes(t *testing.T) {
+	got := string(truncateBytes([]byte("aaaa\nbbbb\ncccc"), 12))
+	if got != "aaaa\nbbbb\n... truncated 1 lines ...\n" {
+		t.Errorf("Unexpected truncation: %q", got)
+	}
+
+	got = string(truncateBytes([]byte("héllo world"), 2))
+	if got != "h\n... truncated 11 bytes ...\n" {
+		t.Errorf("Expected cut on a rune boundary, got %q", got)
+	}
+}
+
+func TestPackSizeLimits(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	os.WriteFile(filepath.Join(tmpDir, "app.min.js"), []byte(strings.Repeat("x", 5000)), 0644)
+	os.WriteFile(filepath.Join(tmpDir, "build.log"), []byte(strings.Repeat("line\n", 100)), 0644)
+	os.WriteFile(filepath.Join(tmpDir, "main.go"), []byte("package main\n"), 0644)
+
+	opts := PackOptions{Dir: tmpDir, MaxFileSize: 1000, MaxLines: 10}
+	_, files, err := Pack(context.Background(), opts)
+	if err != nil {
+		t.Fatalf("Pack failed: %v", err)
+	}
+	if len(files) != 1 || files[0] != "main.go" {
+		t.Errorf("Expected only main.go with skip policy, got %v", files)
+	}
+
+	opts.LimitPolicy = "truncate"
+	archive, _, err := Pack(context.Background(), opts)
+	if err != nil {
+		t.Fatalf("Pack failed: %v", err)
+	}
+	for _, f := range archive.Files {
+		if f.Name == "build.log" && !strings.HasSuffix(string(f.Data), "... truncated 90 lines ...\n") {
+			t.Errorf("build.log not truncated: %q", f.Data)
+		}
+		if f.Name == "app.min.js" && len(f.Data) > 1100 {
+			t.Errorf("app.min.js not truncated: %d bytes", len(f.Data))
+		}
+	}
+
+	opts.LimitPolicy = "fail"
+	if _, _, err := Pack(context.Background(), opts); err == nil {
+		t.Error("Expected fail policy to abort")
+	}
+}
+
+func TestPackMaxTotalSizeKeepsPriority(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	os.WriteFile(filepath.Join(tmpDir, "a.txt"), []byte(strings.Repeat("a", 60)), 0644)
+	os.WriteFile(filepath.Join(tmpDir, "b.txt"), []byte(strings.Repeat("b", 60)), 0644)
+
+	opts := PackOptions{Dir: tmpDir, MaxTotalSize: 100, Priority: []string{"b.txt"}}
+	_, files, err := Pack(context.Background(), opts)
+	if err != nil {
+		t.Fatalf("Pack failed: %v", err)
+	}
+	if len(files) != 1 || files[0] != "b.txt" {
+		t.Errorf("Expected b.txt to be kept, got %v", files)
+	}
+}
+
+func TestPackPerLimitPolicies(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	os.WriteFile(filepath.Join(tmpDir, "app.min.js"), []byte(strings.Repeat("x", 5000)), 0644)
+	os.WriteFile(filepath.Join(tmpDir, "build.log"), []byte(strings.Repeat("line\n", 100)), 0644)
+	os.WriteFile(filepath.Join(tmpDir, "main.go"), []byte("package main\n"), 0644)
+
+	opts := PackOptions{Dir: tmpDir, MaxFileSize: 1000, MaxLines: 10, LimitPolicy: "skip", LinesPolicy: "truncate"}
+	archive, files, err := Pack(context.Background(), opts)
+	if err != nil {
+		t.Fatalf("Pack failed: %v", err)
+	}
+	if got := strings.Join(files, " "); got != "build.log main.go" {
+		t.Errorf("Expected app.min.js skipped and build.log kept, got %s", got)
+	}
+	for _, f := range archive.Files {
+		if f.Name == "build.log" && !strings.HasSuffix(string(f.Data), "... truncated 90 lines ...\n") {
+			t.Errorf("build.log not truncated: %q", f.Data)
+		}
+	}
+
+	opts.FileSizePolicy = "fail"
+	if _, _, err := Pack(context.Background(), opts); err == nil || !strings.Contains(err.Error(), "max-file-size") {
+		t.Errorf("Expected max-file-size policy to abort, got %v", err)
+	}
+
+	opts.FileSizePolicy = "drop"
+	if _, _, err := Pack(context.Background(), opts); err == nil {
+		t.Error("Expected error for unknown per-limit policy")
+	}
+}
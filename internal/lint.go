@@ -0,0 +1,173 @@
+package internal
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"golang.org/x/tools/txtar"
+)
+
+const (
+	LintError   = "error"
+	LintWarning = "warning"
+)
+
+type LintOptions struct {
+	MaxFileSize int64
+}
+
+type LintIssue struct {
+	Line     int    `json:"line,omitempty"`
+	File     string `json:"file,omitempty"`
+	Rule     string `json:"rule"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+}
+
+var windowsReserved = map[string]bool{
+	"CON": true, "PRN": true, "AUX": true, "NUL": true,
+	"COM1": true, "COM2": true, "COM3": true, "COM4": true, "COM5": true,
+	"COM6": true, "COM7": true, "COM8": true, "COM9": true,
+	"LPT1": true, "LPT2": true, "LPT3": true, "LPT4": true, "LPT5": true,
+	"LPT6": true, "LPT7": true, "LPT8": true, "LPT9": true,
+}
+
+type lintMarker struct {
+	line int
+	raw  string
+}
+
+// Lint checks raw archive data for problems txtar.Parse silently accepts.
+// Issues are returned in line order.
+func Lint(data []byte, opts LintOptions) []LintIssue {
+	issues := []LintIssue{}
+	var markers []lintMarker
+
+	lines := strings.SplitAfter(string(data), "\n")
+	for i, line := range lines {
+		text := strings.TrimSuffix(line, "\n")
+		lineNo := i + 1
+
+		if markerName([]byte(text)) != "" {
+			markers = append(markers, lintMarker{line: lineNo, raw: text[3 : len(text)-3]})
+			continue
+		}
+
+		if isEmptyMarker(text) {
+			issues = append(issues, LintIssue{Line: lineNo, Rule: "empty-name", Severity: LintError,
+				Message: "marker with an empty name is treated as content"})
+			continue
+		}
+
+		if looseMarkerName(strings.TrimSuffix(text, "\r")) != "" {
+			issues = append(issues, LintIssue{Line: lineNo, Rule: "marker-like", Severity: LintWarning,
+				Message: fmt.Sprintf("line %q looks like a file marker but is parsed as content", text)})
+		}
+	}
+
+	archive := txtar.Parse(data)
+	seen := make(map[string]int)
+	folded := make(map[string]string)
+
+	for i, f := range archive.Files {
+		m := markers[i]
+		add := func(rule, severity, format string, args ...interface{}) {
+			issues = append(issues, LintIssue{Line: m.line, File: f.Name, Rule: rule, Severity: severity,
+				Message: fmt.Sprintf(format, args...)})
+		}
+
+		if m.raw != f.Name {
+			add("name-whitespace", LintWarning, "extra whitespace around name in marker is dropped")
+		}
+
+		if first, ok := seen[f.Name]; ok {
+			add("duplicate-name", LintError, "duplicate name %q (first at line %d)", f.Name, first)
+		} else {
+			seen[f.Name] = m.line
+			lower := strings.ToLower(f.Name)
+			if other, ok := folded[lower]; ok {
+				add("case-collision", LintWarning, "name %q collides with %q on case-insensitive file systems", f.Name, other)
+			} else {
+				folded[lower] = f.Name
+			}
+		}
+
+		if err := validatePath(filepath.FromSlash(f.Name)); err != nil {
+			add("unsafe-path", LintError, "%v", err)
+		}
+
+		for _, part := range strings.Split(f.Name, "/") {
+			if part != strings.TrimRight(part, " ") {
+				add("trailing-space", LintError, "path component %q ends with a space", part)
+			}
+			if reason := windowsProblem(part); reason != "" {
+				add("windows-name", LintWarning, "path component %q %s", part, reason)
+			}
+		}
+
+		// txtar.Parse adds the final newline itself, so check the raw data.
+		if i == len(archive.Files)-1 && len(f.Data) > 0 && !bytes.HasSuffix(data, []byte("\n")) {
+			add("missing-newline", LintWarning, "content does not end with a newline")
+		}
+
+		if opts.MaxFileSize > 0 && int64(len(f.Data)) > opts.MaxFileSize {
+			add("oversized", LintWarning, "file is %d bytes (limit %d)", len(f.Data), opts.MaxFileSize)
+		}
+	}
+
+	sort.SliceStable(issues, func(i, j int) bool {
+		return issues[i].Line < issues[j].Line
+	})
+	return issues
+}
+
+// isEmptyMarker reports lines shaped like a marker whose name is blank.
+func isEmptyMarker(text string) bool {
+	if text == "-- --" {
+		return true
+	}
+	return len(text) >= 6 && strings.HasPrefix(text, "-- ") && strings.HasSuffix(text, " --") &&
+		strings.TrimSpace(text[3:len(text)-3]) == ""
+}
+
+// windowsProblem returns why a path component cannot be created on Windows,
+// or "" if it can.
+func windowsProblem(part string) string {
+	if part == "" || part == "." || part == ".." {
+		return ""
+	}
+
+	base := strings.ToUpper(part)
+	if i := strings.IndexByte(base, '.'); i >= 0 {
+		base = base[:i]
+	}
+	if windowsReserved[strings.TrimRight(base, " ")] {
+		return "is a reserved device name on Windows"
+	}
+
+	for _, r := range part {
+		if r < 0x20 || strings.ContainsRune(`<>:"|?*\`, r) {
+			return fmt.Sprintf("contains %q, which is not allowed on Windows", r)
+		}
+	}
+
+	if strings.HasSuffix(part, ".") {
+		return "ends with a dot, which Windows strips"
+	}
+	return ""
+}
+
+// CountLintIssues returns the number of errors and warnings in issues.
+func CountLintIssues(issues []LintIssue) (errors, warnings int) {
+	for _, issue := range issues {
+		if issue.Severity == LintError {
+			errors++
+		} else {
+			warnings++
+		}
+	}
+	return errors, warnings
+}
@@ -0,0 +1,92 @@
+package internal
+
+import (
+	"testing"
+)
+
+func lintRules(issues []LintIssue) map[string]int {
+	rules := make(map[string]int)
+	for _, issue := range issues {
+		rules[issue.Rule]++
+	}
+	return rules
+}
+
+func TestLintCleanArchive(t *testing.T) {
+	issues := Lint([]byte("comment\n-- a.txt --\nhello\n-- dir/b.go --\npackage b\n"), LintOptions{MaxFileSize: 1024})
+	if len(issues) != 0 {
+		t.Errorf("Expected no issues, got %+v", issues)
+	}
+}
+
+func TestLintIgnoresDashLines(t *testing.T) {
+	input := "-- notes.md --\n" +
+		"Title\n" +
+		"-----\n" +
+		"---\n" +
+		"-----BEGIN PUBLIC KEY-----\n" +
+		"abc\n" +
+		"-----END PUBLIC KEY-----\n"
+
+	if issues := Lint([]byte(input), LintOptions{}); len(issues) != 0 {
+		t.Errorf("Expected no issues, got %+v", issues)
+	}
+}
+
+func TestLintReportsProblems(t *testing.T) {
+	input := "-- a.txt --\n" +
+		"one\n" +
+		"-- a.txt --\n" +
+		"two\n" +
+		"-- A.TXT --\n" +
+		"three\n" +
+		"--  --\n" +
+		"-- b.txt  --\n" +
+		"—  c.txt —\n" +
+		"-- ../evil --\n" +
+		"x\n" +
+		"-- dir/con.txt --\n" +
+		"x\n" +
+		"-- big.txt --\n" +
+		"0123456789abcdef"
+
+	issues := Lint([]byte(input), LintOptions{MaxFileSize: 8})
+	rules := lintRules(issues)
+
+	for _, rule := range []string{"duplicate-name", "case-collision", "empty-name", "name-whitespace",
+		"marker-like", "unsafe-path", "windows-name", "missing-newline", "oversized"} {
+		if rules[rule] == 0 {
+			t.Errorf("Expected a %s issue, got %+v", rule, issues)
+		}
+	}
+
+	errors, warnings := CountLintIssues(issues)
+	if errors != 3 {
+		t.Errorf("Expected 3 errors, got %d: %+v", errors, issues)
+	}
+	if warnings == 0 {
+		t.Error("Expected warnings")
+	}
+
+	for i := 1; i < len(issues); i++ {
+		if issues[i].Line < issues[i-1].Line {
+			t.Errorf("Issues not in line order: %+v", issues)
+		}
+	}
+}
+
+func TestWindowsProblem(t *testing.T) {
+	tests := map[string]bool{
+		"main.go":  false,
+		"NUL":      true,
+		"com1.txt": true,
+		"a:b":      true,
+		"trail.":   true,
+		"console":  false,
+	}
+	for part, want := range tests {
+		if got := windowsProblem(part) != ""; got != want {
+			t.Errorf("windowsProblem(%q) = %v, want %v", part, got, want)
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package internal
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+type ListOptions struct {
+	Include   []string
+	Exclude   []string
+	Sort      string
+	Tokenizer Tokenizer
+}
+
+type EntryInfo struct {
+	Name   string `json:"name"`
+	Size   int    `json:"size"`
+	Lines  int    `json:"lines"`
+	Tokens int    `json:"tokens"`
+	Binary bool   `json:"binary"`
+	SHA256 string `json:"sha256"`
+}
+
+func ListEntries(r io.Reader, opts ListOptions) ([]EntryInfo, error) {
+	filter := &Filter{
+		include: opts.Include,
+		exclude: opts.Exclude,
+	}
+
+	tok := opts.Tokenizer
+	if tok == nil {
+		tok = BPETokenizer{}
+	}
+
+	var entries []EntryInfo
+	reader := NewReader(r)
+	for {
+		file, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if !filter.ShouldInclude(file.Name) {
+			continue
+		}
+
+		sum := sha256.Sum256(file.Data)
+		entries = append(entries, EntryInfo{
+			Name:   file.Name,
+			Size:   len(file.Data),
+			Lines:  countLines(file.Data),
+			Tokens: tok.CountTokens(file.Data),
+			Binary: isBinaryContent(file.Data),
+			SHA256: hex.EncodeToString(sum[:]),
+		})
+	}
+
+	switch opts.Sort {
+	case "", "none":
+	case "name":
+		sort.SliceStable(entries, func(i, j int) bool {
+			return entries[i].Name < entries[j].Name
+		})
+	case "size":
+		sort.SliceStable(entries, func(i, j int) bool {
+			return entries[i].Size > entries[j].Size
+		})
+	default:
+		return nil, fmt.Errorf("unknown sort key %q (want name or size)", opts.Sort)
+	}
+
+	return entries, nil
+}
+
+func countLines(data []byte) int {
+	n := bytes.Count(data, []byte("\n"))
+	if len(data) > 0 && data[len(data)-1] != '\n' {
+		n++
+	}
+	return n
+}
+
+func isBinaryContent(data []byte) bool {
+	return bytes.Contains(data[:min(1024, len(data))], []byte{0})
+}
+
+func PrintLong(w io.Writer, entries []EntryInfo) {
+	for _, e := range entries {
+		kind := "text"
+		if e.Binary {
+			kind = "bin"
+		}
+		fmt.Fprintf(w, "%10d %7d %8d %-4s %s  %s\n", e.Size, e.Lines, e.Tokens, kind, e.SHA256, e.Name)
+	}
+}
+
+func PrintSummary(w io.Writer, entries []EntryInfo) {
+	size, lines, tokens := 0, 0, 0
+	for _, e := range entries {
+		size += e.Size
+		lines += e.Lines
+		tokens += e.Tokens
+	}
+	fmt.Fprintf(w, "total: %d files, %d bytes, %d lines, ~%d tokens\n", len(entries), size, lines, tokens)
+}
+
+type treeNode struct {
+	name     string
+	children []*treeNode
+	index    map[string]*treeNode
+}
+
+func PrintTree(w io.Writer, entries []EntryInfo) {
+	root := &treeNode{index: map[string]*treeNode{}}
+	for _, e := range entries {
+		node := root
+		for _, part := range strings.Split(e.Name, "/") {
+			child, ok := node.index[part]
+			if !ok {
+				child = &treeNode{name: part, index: map[string]*treeNode{}}
+				node.index[part] = child
+				node.children = append(node.children, child)
+			}
+			node = child
+		}
+	}
+
+	fmt.Fprintln(w, ".")
+	printTreeChildren(w, root, "")
+}
+
+func printTreeChildren(w io.Writer, node *treeNode, prefix string) {
+	for i, child := range node.children {
+		branch, indent := "├── ", "│   "
+		if i == len(node.children)-1 {
+			branch, indent = "└── ", "    "
+		}
+		fmt.Fprintf(w, "%s%s%s\n", prefix, branch, child.name)
+		printTreeChildren(w, child, prefix+indent)
+	}
+}
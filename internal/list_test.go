@@ -0,0 +1,45 @@
+package internal
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestListEntries(t *testing.T) {
+	archive := "-- b/big.txt --\none\ntwo\nthree\n-- a.bin --\nx\x00y\n-- b/skip.log --\nlog\n"
+
+	entries, err := ListEntries(strings.NewReader(archive), ListOptions{
+		Exclude: []string{"**/*.log"},
+		Sort:    "name",
+	})
+	if err != nil {
+		t.Fatalf("ListEntries failed: %v", err)
+	}
+
+	if len(entries) != 2 {
+		t.Fatalf("Expected 2 entries, got %d", len(entries))
+	}
+
+	if entries[0].Name != "a.bin" || !entries[0].Binary {
+		t.Errorf("Expected binary a.bin first, got %+v", entries[0])
+	}
+
+	if entries[1].Lines != 3 || entries[1].Size != 14 || entries[1].Binary {
+		t.Errorf("Unexpected stats for b/big.txt: %+v", entries[1])
+	}
+
+	if len(entries[1].SHA256) != 64 {
+		t.Errorf("Expected hex SHA-256, got %q", entries[1].SHA256)
+	}
+}
+
+func TestPrintTree(t *testing.T) {
+	var out bytes.Buffer
+	PrintTree(&out, []EntryInfo{{Name: "a.go"}, {Name: "b/b.go"}, {Name: "b/c/d.txt"}})
+
+	want := ".\n├── a.go\n└── b\n    ├── b.go\n    └── c\n        └── d.txt\n"
+	if out.String() != want {
+		t.Errorf("Unexpected tree:\n%s", out.String())
+	}
+}
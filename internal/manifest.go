@@ -0,0 +1,136 @@
+package internal
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"golang.org/x/tools/txtar"
+)
+
+const (
+	manifestHeader = "# txtar manifest v1"
+	manifestFile   = "# sha256 "
+	manifestDigest = "# digest sha256 "
+)
+
+type Manifest struct {
+	Names  []string
+	Hashes map[string]string
+	Digest string
+}
+
+func fileHash(data []byte) string {
+	sum := sha256.Sum256(fixNL(data))
+	return hex.EncodeToString(sum[:])
+}
+
+// manifestDigestOf covers every name and hash in order, so reordering,
+// dropping or adding entries changes it.
+func manifestDigestOf(names []string, hashes map[string]string) string {
+	h := sha256.New()
+	for _, name := range names {
+		fmt.Fprintf(h, "%s  %s\n", hashes[name], name)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// BuildManifest returns the manifest block for files. Hashes are taken over
+// the data as txtar.Format writes it, so they survive a format/parse round trip.
+func BuildManifest(files []txtar.File) []byte {
+	var buf bytes.Buffer
+	names := make([]string, 0, len(files))
+	hashes := make(map[string]string, len(files))
+
+	fmt.Fprintln(&buf, manifestHeader)
+	for _, f := range files {
+		hash := fileHash(f.Data)
+		names = append(names, f.Name)
+		hashes[f.Name] = hash
+		fmt.Fprintf(&buf, "%s%s  %s\n", manifestFile, hash, f.Name)
+	}
+	fmt.Fprintf(&buf, "%s%s\n", manifestDigest, manifestDigestOf(names, hashes))
+
+	return buf.Bytes()
+}
+
+func ParseManifest(comment []byte) (*Manifest, error) {
+	scanner := bufio.NewScanner(bytes.NewReader(comment))
+	inManifest := false
+	m := &Manifest{Hashes: make(map[string]string)}
+
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == manifestHeader:
+			inManifest = true
+		case !inManifest:
+		case strings.HasPrefix(line, manifestFile):
+			hash, name, ok := strings.Cut(strings.TrimPrefix(line, manifestFile), "  ")
+			if !ok {
+				return nil, fmt.Errorf("malformed manifest line: %q", line)
+			}
+			m.Names = append(m.Names, name)
+			m.Hashes[name] = hash
+		case strings.HasPrefix(line, manifestDigest):
+			m.Digest = strings.TrimPrefix(line, manifestDigest)
+			return m, nil
+		default:
+			return nil, fmt.Errorf("malformed manifest line: %q", line)
+		}
+	}
+
+	if inManifest {
+		return nil, fmt.Errorf("manifest is truncated: missing digest")
+	}
+	return nil, fmt.Errorf("archive has no manifest")
+}
+
+// VerifyManifest checks the archive entries against the manifest in its
+// comment and returns one message per problem found.
+func VerifyManifest(archive *txtar.Archive) ([]string, error) {
+	m, err := ParseManifest(archive.Comment)
+	if err != nil {
+		return nil, err
+	}
+
+	var problems []string
+	if manifestDigestOf(m.Names, m.Hashes) != m.Digest {
+		problems = append(problems, "manifest digest mismatch: manifest was edited")
+	}
+
+	seen := make(map[string]bool)
+	var names []string
+	for _, f := range archive.Files {
+		names = append(names, f.Name)
+		if seen[f.Name] {
+			problems = append(problems, fmt.Sprintf("%s: duplicate entry", f.Name))
+			continue
+		}
+		seen[f.Name] = true
+
+		want, ok := m.Hashes[f.Name]
+		if !ok {
+			problems = append(problems, fmt.Sprintf("%s: not in manifest", f.Name))
+			continue
+		}
+		if fileHash(f.Data) != want {
+			problems = append(problems, fmt.Sprintf("%s: checksum mismatch", f.Name))
+		}
+	}
+
+	for _, name := range m.Names {
+		if !seen[name] {
+			problems = append(problems, fmt.Sprintf("%s: missing from archive", name))
+		}
+	}
+
+	if len(problems) == 0 && strings.Join(names, "\n") != strings.Join(m.Names, "\n") {
+		problems = append(problems, "entries are out of manifest order")
+	}
+
+	return problems, nil
+}
@@ -0,0 +1,64 @@
+package internal
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/tools/txtar"
+)
+
+func packWithManifest(t *testing.T) *txtar.Archive {
+	t.Helper()
+
+	tmpDir := t.TempDir()
+	os.WriteFile(filepath.Join(tmpDir, "a.txt"), []byte("no trailing newline"), 0644)
+	os.WriteFile(filepath.Join(tmpDir, "b.txt"), []byte("b\n"), 0644)
+
+	archive, _, err := Pack(context.Background(), PackOptions{Dir: tmpDir, Manifest: true, CommentTemplate: "hello"})
+	if err != nil {
+		t.Fatalf("Pack failed: %v", err)
+	}
+
+	// Round trip through the serialized form like a real consumer would.
+	return txtar.Parse(txtar.Format(archive))
+}
+
+func TestVerifyManifest(t *testing.T) {
+	archive := packWithManifest(t)
+
+	problems, err := VerifyManifest(archive)
+	if err != nil || len(problems) != 0 {
+		t.Fatalf("Expected clean verification, got %v, %v", problems, err)
+	}
+
+	archive.Files[0].Data = []byte("tampered\n")
+	archive.Files = archive.Files[:1]
+
+	problems, err = VerifyManifest(archive)
+	if err != nil {
+		t.Fatalf("VerifyManifest failed: %v", err)
+	}
+	if len(problems) != 2 {
+		t.Errorf("Expected checksum and missing-file problems, got %v", problems)
+	}
+}
+
+func TestUnpackVerify(t *testing.T) {
+	archive := packWithManifest(t)
+	archive.Files[1].Data = []byte("edited\n")
+
+	err := Unpack(archive, UnpackOptions{Dir: t.TempDir(), Verify: true})
+	if err == nil {
+		t.Fatal("Expected tampered archive to be rejected")
+	}
+
+	if err := Unpack(archive, UnpackOptions{Dir: t.TempDir(), Verify: true, Force: true}); err != nil {
+		t.Fatalf("Expected --force to extract anyway, got %v", err)
+	}
+
+	if _, err := VerifyManifest(&txtar.Archive{}); err == nil {
+		t.Error("Expected error for archive without manifest")
+	}
+}
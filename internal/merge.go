@@ -0,0 +1,115 @@
+package internal
+
+import (
+	"bytes"
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/tools/txtar"
+)
+
+type MergeInput struct {
+	Source  string
+	Prefix  string
+	Archive *txtar.Archive
+}
+
+type MergeOptions struct {
+	Policy string
+	Suffix string
+}
+
+// ParseMergeInput splits an "archive=prefix/" argument into its parts.
+func ParseMergeInput(arg string) (source, prefix string) {
+	if i := strings.LastIndex(arg, "="); i > 0 {
+		return arg[:i], arg[i+1:]
+	}
+	return arg, ""
+}
+
+func Merge(inputs []MergeInput, opts MergeOptions) (*txtar.Archive, error) {
+	if opts.Policy == "" {
+		opts.Policy = "error"
+	}
+	if opts.Suffix == "" {
+		opts.Suffix = "~"
+	}
+
+	switch opts.Policy {
+	case "first", "last", "error", "rename":
+	default:
+		return nil, fmt.Errorf("unknown conflict policy %q (want first, last, error, or rename)", opts.Policy)
+	}
+
+	// A bad prefix would only surface when the merged archive is unpacked.
+	for _, in := range inputs {
+		if in.Prefix == "" {
+			continue
+		}
+		if err := validatePath(filepath.FromSlash(in.Prefix)); err != nil {
+			return nil, fmt.Errorf("invalid prefix %q for %s: %w", in.Prefix, in.Source, err)
+		}
+	}
+
+	merged := &txtar.Archive{}
+	index := make(map[string]int)
+	var comments bytes.Buffer
+
+	for _, in := range inputs {
+		if len(in.Archive.Comment) > 0 {
+			comments.Write(fixNL(in.Archive.Comment))
+		}
+
+		for _, f := range in.Archive.Files {
+			name := f.Name
+			if in.Prefix != "" {
+				name = path.Join(in.Prefix, name)
+			}
+
+			i, exists := index[name]
+			if !exists {
+				index[name] = len(merged.Files)
+				merged.Files = append(merged.Files, txtar.File{Name: name, Data: f.Data})
+				continue
+			}
+
+			switch opts.Policy {
+			case "first":
+			case "last":
+				merged.Files[i].Data = f.Data
+			case "error":
+				return nil, fmt.Errorf("duplicate file %q in %s", name, in.Source)
+			case "rename":
+				renamed := uniqueName(name, opts.Suffix, index)
+				index[renamed] = len(merged.Files)
+				merged.Files = append(merged.Files, txtar.File{Name: renamed, Data: f.Data})
+			}
+		}
+	}
+
+	merged.Comment = comments.Bytes()
+	return JoinSplitFiles(merged)
+}
+
+func uniqueName(name, suffix string, taken map[string]int) string {
+	ext := path.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+	for n := 1; ; n++ {
+		candidate := fmt.Sprintf("%s%s%d%s", base, suffix, n, ext)
+		if _, exists := taken[candidate]; !exists {
+			return candidate
+		}
+	}
+}
+
+func fixNL(data []byte) []byte {
+	if len(data) == 0 || data[len(data)-1] == '\n' {
+		return data
+	}
+	d := make([]byte, len(data)+1)
+	copy(d, data)
+	d[len(data)] = '\n'
+	return d
+}
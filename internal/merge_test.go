@@ -0,0 +1,56 @@
+package internal
+
+import (
+	"testing"
+
+	"golang.org/x/tools/txtar"
+)
+
+func mergeInputs() []MergeInput {
+	return []MergeInput{
+		{Source: "a", Archive: txtar.Parse([]byte("from a\n-- x.go --\na\n-- y.go --\na\n"))},
+		{Source: "b", Archive: txtar.Parse([]byte("from b\n-- x.go --\nb\n"))},
+		{Source: "c", Prefix: "vendor/", Archive: txtar.Parse([]byte("-- x.go --\nc\n"))},
+	}
+}
+
+func TestMergePolicies(t *testing.T) {
+	tests := []struct {
+		policy string
+		want   string
+	}{
+		{"first", "from a\nfrom b\n-- x.go --\na\n-- y.go --\na\n-- vendor/x.go --\nc\n"},
+		{"last", "from a\nfrom b\n-- x.go --\nb\n-- y.go --\na\n-- vendor/x.go --\nc\n"},
+		{"rename", "from a\nfrom b\n-- x.go --\na\n-- y.go --\na\n-- x~1.go --\nb\n-- vendor/x.go --\nc\n"},
+	}
+
+	for _, tt := range tests {
+		merged, err := Merge(mergeInputs(), MergeOptions{Policy: tt.policy})
+		if err != nil {
+			t.Fatalf("Merge(%s) failed: %v", tt.policy, err)
+		}
+		if got := string(txtar.Format(merged)); got != tt.want {
+			t.Errorf("Merge(%s) =\n%s\nwant:\n%s", tt.policy, got, tt.want)
+		}
+	}
+
+	if _, err := Merge(mergeInputs(), MergeOptions{Policy: "error"}); err == nil {
+		t.Error("Expected error for duplicate names")
+	}
+}
+
+func TestParseMergeInput(t *testing.T) {
+	source, prefix := ParseMergeInput("b.txtar=vendor/")
+	if source != "b.txtar" || prefix != "vendor/" {
+		t.Errorf("Unexpected split: %q %q", source, prefix)
+	}
+}
+
+func TestMergeRejectsUnsafePrefix(t *testing.T) {
+	for _, prefix := range []string{"../", "/abs", "a/../../b", `..\x`, "C:/x"} {
+		inputs := []MergeInput{{Source: "a", Prefix: prefix, Archive: txtar.Parse([]byte("-- x.go --\na\n"))}}
+		if _, err := Merge(inputs, MergeOptions{}); err == nil {
+			t.Errorf("Expected error for prefix %q", prefix)
+		}
+	}
+}
@@ -0,0 +1,170 @@
+package internal
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/storer"
+)
+
+// orderFiles sorts files in place by opts.Order. Without one, files keep
+// the order they were collected in.
+//
+//	path        by path
+//	size        smallest first
+//	mtime       most recently modified first
+//	git-recent  most recently committed first, uncommitted files before all
+//	custom      by --priority glob, unmatched files next, test files last
+//
+// Ties are broken by path.
+func orderFiles(files []string, contents map[string][]byte, opts PackOptions) error {
+	dir := opts.Dir
+	if dir == "" {
+		dir = "."
+	}
+
+	// compare returns a negative number when a belongs before b.
+	var compare func(a, b string) int
+	switch opts.Order {
+	case "":
+		return nil
+	case "path":
+	case "size":
+		compare = func(a, b string) int {
+			return len(contents[a]) - len(contents[b])
+		}
+	case "mtime":
+		mtimes := make(map[string]time.Time, len(files))
+		for _, f := range files {
+			if info, err := os.Stat(filepath.Join(dir, f)); err == nil {
+				mtimes[f] = info.ModTime()
+			}
+		}
+		compare = func(a, b string) int {
+			return mtimes[b].Compare(mtimes[a])
+		}
+	case "git-recent":
+		times, err := lastCommitTimes(dir, files)
+		if err != nil {
+			return err
+		}
+		compare = func(a, b string) int {
+			ta, oka := times[a]
+			tb, okb := times[b]
+			switch {
+			case oka && !okb:
+				return 1
+			case !oka && okb:
+				return -1
+			}
+			return tb.Compare(ta)
+		}
+	case "custom":
+		compare = func(a, b string) int {
+			return priorityRank(filepath.ToSlash(a), opts.Priority) - priorityRank(filepath.ToSlash(b), opts.Priority)
+		}
+	default:
+		return fmt.Errorf("unknown order %q (want path, size, mtime, git-recent, or custom)", opts.Order)
+	}
+
+	sort.SliceStable(files, func(i, j int) bool {
+		if compare != nil {
+			if c := compare(files[i], files[j]); c != 0 {
+				return c < 0
+			}
+		}
+		return filepath.ToSlash(files[i]) < filepath.ToSlash(files[j])
+	})
+	return nil
+}
+
+// lastCommitTimes walks history from HEAD and returns, for each of files
+// (relative to dir), the time of the latest commit that changed it. Files
+// that were never committed are absent from the result.
+func lastCommitTimes(dir string, files []string) (map[string]time.Time, error) {
+	repo, err := git.PlainOpenWithOptions(dir, &git.PlainOpenOptions{DetectDotGit: true})
+	if err != nil {
+		return nil, fmt.Errorf("--order git-recent requires a git repository: %w", err)
+	}
+
+	w, err := repo.Worktree()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get worktree: %w", err)
+	}
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	prefix, err := filepath.Rel(w.Filesystem.Root(), absDir)
+	if err != nil {
+		return nil, err
+	}
+
+	// Map repository paths back to the names the caller uses.
+	pending := make(map[string]string, len(files))
+	for _, f := range files {
+		pending[path.Join(filepath.ToSlash(prefix), filepath.ToSlash(f))] = f
+	}
+
+	times := make(map[string]time.Time, len(files))
+	head, err := repo.Head()
+	if err != nil {
+		// A repository without commits has nothing to order by.
+		return times, nil
+	}
+
+	commits, err := repo.Log(&git.LogOptions{From: head.Hash(), Order: git.LogOrderCommitterTime})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get log: %w", err)
+	}
+	defer commits.Close()
+
+	found := func(name string, when time.Time) {
+		if f, ok := pending[name]; ok {
+			times[f] = when
+			delete(pending, name)
+		}
+	}
+
+	err = commits.ForEach(func(c *object.Commit) error {
+		tree, err := c.Tree()
+		if err != nil {
+			return err
+		}
+
+		var parentTree *object.Tree
+		if c.NumParents() > 0 {
+			parent, err := c.Parent(0)
+			if err != nil {
+				return err
+			}
+			if parentTree, err = parent.Tree(); err != nil {
+				return err
+			}
+		}
+
+		changes, err := object.DiffTree(parentTree, tree)
+		if err != nil {
+			return err
+		}
+		for _, ch := range changes {
+			found(ch.To.Name, c.Committer.When)
+		}
+
+		if len(pending) == 0 {
+			return storer.ErrStop
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return times, nil
+}
@@ -0,0 +1,92 @@
+package internal
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+func TestOrderFiles(t *testing.T) {
+	contents := map[string][]byte{
+		"README.md":        []byte("readme"),
+		"go.mod":           []byte("module m\n"),
+		"cmd/main.go":      []byte("package main\n\nfunc main() {}\n"),
+		"cmd/main_test.go": []byte("package main\n"),
+		"a.txt":            []byte("a"),
+	}
+	names := func() []string {
+		return []string{"cmd/main_test.go", "go.mod", "a.txt", "cmd/main.go", "README.md"}
+	}
+
+	tests := []struct {
+		opts PackOptions
+		want string
+	}{
+		{PackOptions{}, "cmd/main_test.go go.mod a.txt cmd/main.go README.md"},
+		{PackOptions{Order: "path"}, "README.md a.txt cmd/main.go cmd/main_test.go go.mod"},
+		{PackOptions{Order: "size"}, "a.txt README.md go.mod cmd/main_test.go cmd/main.go"},
+		{PackOptions{Order: "custom", Priority: []string{"README.md", "go.mod", "cmd/**"}}, "README.md go.mod cmd/main.go cmd/main_test.go a.txt"},
+		{PackOptions{Order: "custom"}, "README.md a.txt cmd/main.go go.mod cmd/main_test.go"},
+	}
+	for _, tt := range tests {
+		files := names()
+		if err := orderFiles(files, contents, tt.opts); err != nil {
+			t.Fatalf("orderFiles(%q) failed: %v", tt.opts.Order, err)
+		}
+		if got := strings.Join(files, " "); got != tt.want {
+			t.Errorf("Order %q: got %s, want %s", tt.opts.Order, got, tt.want)
+		}
+	}
+
+	if err := orderFiles(names(), contents, PackOptions{Order: "random"}); err == nil {
+		t.Error("Expected error for unknown order")
+	}
+}
+
+func TestPackOrderGitRecent(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	repo, err := git.PlainInit(tmpDir, false)
+	if err != nil {
+		t.Fatalf("PlainInit failed: %v", err)
+	}
+	w, err := repo.Worktree()
+	if err != nil {
+		t.Fatalf("Worktree failed: %v", err)
+	}
+
+	when := time.Now().Add(-time.Hour)
+	commit := func(name string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(tmpDir, name), []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Add(name); err != nil {
+			t.Fatalf("Add failed: %v", err)
+		}
+		when = when.Add(time.Minute)
+		sig := &object.Signature{Name: "test", Email: "test@example.com", When: when}
+		if _, err := w.Commit("add "+name, &git.CommitOptions{Author: sig, Committer: sig}); err != nil {
+			t.Fatalf("Commit failed: %v", err)
+		}
+	}
+
+	commit("old.txt")
+	commit("middle.txt")
+	commit("new.txt")
+	os.WriteFile(filepath.Join(tmpDir, "untracked.txt"), []byte("u"), 0644)
+
+	_, files, err := Pack(context.Background(), PackOptions{Dir: tmpDir, Exclude: []string{".git/**"}, Order: "git-recent", DryRun: true})
+	if err != nil {
+		t.Fatalf("Pack failed: %v", err)
+	}
+	if got := strings.Join(files, " "); got != "untracked.txt new.txt middle.txt old.txt" {
+		t.Errorf("Unexpected order: %s", got)
+	}
+}
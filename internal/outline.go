@@ -0,0 +1,79 @@
+package internal
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+)
+
+// DefaultOutlineLines is how many lines of a non-Go file an outline keeps.
+const DefaultOutlineLines = 20
+
+// goOutline reduces a Go file to its package clause, imports, type
+// declarations and function signatures, with their doc comments.
+func goOutline(name string, src []byte) ([]byte, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, name, src, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+
+	var dropped []posRange
+	var decls []ast.Decl
+	for _, decl := range file.Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			if d.Body != nil {
+				dropped = append(dropped, posRange{d.Body.Pos(), d.Body.End()})
+				d.Body = nil
+			}
+		case *ast.GenDecl:
+			if d.Tok == token.CONST || d.Tok == token.VAR {
+				dropped = append(dropped, declRange(d, d.Doc))
+				continue
+			}
+		}
+		decls = append(decls, decl)
+	}
+	file.Decls = decls
+
+	return printGoFile(fset, file, dropped)
+}
+
+// outlineFile returns the outline of one file: a Go skeleton, or the first
+// lines of anything else.
+func outlineFile(name string, data []byte, lines int) []byte {
+	if isBinaryContent(data) {
+		return []byte(fmt.Sprintf("... binary file, %d bytes ...\n", len(data)))
+	}
+
+	if filepath.Ext(name) == ".go" {
+		out, err := goOutline(name, data)
+		if err == nil {
+			return out
+		}
+		fmt.Fprintf(os.Stderr, "Outline: %s: %v; keeping the first %d lines\n", name, err, lines)
+	}
+
+	return truncateLines(data, lines)
+}
+
+// applyOutline outlines every file except those matching opts.Include,
+// which keep their full contents.
+func applyOutline(files []string, contents map[string][]byte, opts PackOptions) {
+	lines := opts.OutlineLines
+	if lines <= 0 {
+		lines = DefaultOutlineLines
+	}
+
+	full := &Filter{include: opts.Include}
+	for _, f := range files {
+		if len(opts.Include) > 0 && full.ShouldInclude(filepath.ToSlash(f)) {
+			continue
+		}
+		contents[f] = outlineFile(f, contents[f], lines)
+	}
+}
@@ -0,0 +1,81 @@
+package internal
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGoOutline(t *testing.T) {
+	src := `package p
+
+import "fmt"
+
+const limit = 3
+
+var cache = map[string]int{}
+
+// T is a type.
+type T struct{ N int }
+
+// String formats t.
+func (t T) String() string {
+	// inside
+	return fmt.Sprint(t.N)
+}
+`
+	out, err := goOutline("p.go", []byte(src))
+	if err != nil {
+		t.Fatalf("goOutline failed: %v", err)
+	}
+	want := `package p
+
+import "fmt"
+
+// T is a type.
+type T struct{ N int }
+
+// String formats t.
+func (t T) String() string
+`
+	if string(out) != want {
+		t.Errorf("Unexpected outline:\n%s\nwant:\n%s", out, want)
+	}
+}
+
+func TestPackOutline(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	os.WriteFile(filepath.Join(tmpDir, "main.go"), []byte("package main\n\nfunc main() {\n\tprintln(\"hi\")\n}\n"), 0644)
+	os.WriteFile(filepath.Join(tmpDir, "keep.go"), []byte("package main\n\nfunc keep() {\n\tprintln(\"kept\")\n}\n"), 0644)
+	os.WriteFile(filepath.Join(tmpDir, "notes.txt"), []byte("1\n2\n3\n4\n"), 0644)
+	os.WriteFile(filepath.Join(tmpDir, "broken.go"), []byte("package main\nfunc {\n"), 0644)
+
+	opts := PackOptions{Dir: tmpDir, Outline: true, OutlineLines: 2, Include: []string{"keep.go"}}
+	archive, _, err := Pack(context.Background(), opts)
+	if err != nil {
+		t.Fatalf("Pack failed: %v", err)
+	}
+
+	got := make(map[string]string)
+	for _, f := range archive.Files {
+		got[f.Name] = string(f.Data)
+	}
+	if len(got) != 4 {
+		t.Fatalf("Expected all 4 files, got %v", got)
+	}
+	if got["main.go"] != "package main\n\nfunc main()\n" {
+		t.Errorf("Unexpected main.go outline: %q", got["main.go"])
+	}
+	if !strings.Contains(got["keep.go"], `println("kept")`) {
+		t.Errorf("keep.go should be packed in full: %q", got["keep.go"])
+	}
+	if got["notes.txt"] != "1\n2\n... truncated 2 lines ...\n" {
+		t.Errorf("Unexpected notes.txt outline: %q", got["notes.txt"])
+	}
+	if got["broken.go"] != "package main\nfunc {\n" {
+		t.Errorf("Unparsable Go file should keep its first lines: %q", got["broken.go"])
+	}
+}
@@ -18,28 +18,52 @@ import (
 )
 
 type PackOptions struct {
-	Dir           string
-	Output        string
-	Include       []string
-	Exclude       []string
-	Git           bool
-	Diff          bool
-	Commit        string
-	Since         int
-	Staged        bool
-	Worktree      bool
-	StripPrefix   string
-	DryRun        bool
-	IgnoreBinary  bool
-	TxtarIgnore   string
+	Dir             string
+	Output          string
+	Include         []string
+	Exclude         []string
+	Git             bool
+	Diff            bool
+	Commit          string
+	Since           int
+	Staged          bool
+	Worktree        bool
+	StripPrefix     string
+	DryRun          bool
+	IgnoreBinary    bool
+	TxtarIgnore     string
+	MaxTokens       int
+	Tokenizer       string
+	Priority        []string
+	Header          bool
+	CommentTemplate string
+	Version         string
+	Manifest        bool
+	PreHooks        []string
+	Secrets         string
+	SecretRules     []SecretRule
+	MaxFileSize     int64
+	MaxTotalSize    int64
+	MaxLines        int
+	LimitPolicy     string
+	FileSizePolicy  string
+	TotalSizePolicy string
+	LinesPolicy     string
+	Transforms      []TransformRule
+	GoPackages      []string
+	WithTests       bool
+	ExportsOnly     bool
+	Outline         bool
+	OutlineLines    int
+	Order           string
 }
 
 type Filter struct {
-	include       []string
-	exclude       []string
-	gitignore     []string
-	txtarignore   []string
-	ignoreBinary  bool
+	include      []string
+	exclude      []string
+	gitignore    []string
+	txtarignore  []string
+	ignoreBinary bool
 }
 
 func NewFilter(opts PackOptions) (*Filter, error) {
@@ -146,17 +170,41 @@ func isBinaryFile(path string) (bool, error) {
 }
 
 func Pack(ctx context.Context, opts PackOptions) (*txtar.Archive, []string, error) {
-	filter, err := NewFilter(opts)
+	// In outline mode --include picks the files kept in full, not the
+	// files packed.
+	filterOpts := opts
+	if opts.Outline {
+		filterOpts.Include = nil
+	}
+
+	filter, err := NewFilter(filterOpts)
 	if err != nil {
 		return nil, nil, err
 	}
 
+	// Pre hooks run before files are collected so that formatters and
+	// generators shape the archive. They get no file list, since nothing
+	// is selected yet, and do not run for dry runs, which must not change
+	// the tree.
+	if len(opts.PreHooks) > 0 && !opts.DryRun {
+		dir := opts.Dir
+		if dir == "" {
+			dir = "."
+		}
+		if err := RunHooks("pack.pre", opts.PreHooks, dir, nil); err != nil {
+			return nil, nil, err
+		}
+	}
+
 	var files []string
 	var fileContents map[string][]byte
 
-	if opts.Git {
+	switch {
+	case len(opts.GoPackages) > 0:
+		files, fileContents, err = packGoPackages(opts, filter)
+	case opts.Git:
 		files, fileContents, err = packGit(opts, filter)
-	} else {
+	default:
 		files, fileContents, err = packDir(opts, filter)
 	}
 
@@ -164,11 +212,62 @@ func Pack(ctx context.Context, opts PackOptions) (*txtar.Archive, []string, erro
 		return nil, nil, err
 	}
 
+	if opts.ExportsOnly {
+		applyExportsOnly(files, fileContents)
+	}
+
+	if opts.Outline {
+		applyOutline(files, fileContents, opts)
+	}
+
+	var transforms []string
+	if len(opts.Transforms) > 0 {
+		transforms = applyTransforms(files, fileContents, opts.Transforms)
+	}
+
+	if limitsSet(opts) {
+		files, err = applySizeLimits(files, fileContents, opts)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if opts.MaxTokens > 0 {
+		files, err = applyTokenBudget(files, fileContents, opts)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if err := applySecretPolicy(files, fileContents, opts); err != nil {
+		return nil, nil, err
+	}
+
+	if err := orderFiles(files, fileContents, opts); err != nil {
+		return nil, nil, err
+	}
+
 	if opts.DryRun {
 		return nil, files, nil
 	}
 
 	archive := &txtar.Archive{}
+	if opts.Header || opts.CommentTemplate != "" {
+		text := opts.CommentTemplate
+		if text == "" {
+			text = DefaultCommentTemplate
+		}
+
+		meta := collectMetadata(opts, len(files))
+		meta.Transforms = transforms
+		archive.Comment, err = RenderComment(text, meta)
+		if err != nil {
+			return nil, nil, err
+		}
+	} else if len(transforms) > 0 {
+		archive.Comment = []byte("transforms: " + strings.Join(transforms, ", ") + "\n")
+	}
+
 	for _, file := range files {
 		relativePath := file
 		if opts.StripPrefix != "" {
@@ -185,6 +284,10 @@ func Pack(ctx context.Context, opts PackOptions) (*txtar.Archive, []string, erro
 		})
 	}
 
+	if opts.Manifest {
+		archive.Comment = append(fixNL(archive.Comment), BuildManifest(archive.Files)...)
+	}
+
 	return archive, files, nil
 }
 
@@ -0,0 +1,133 @@
+package internal
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"os"
+	"strings"
+
+	"golang.org/x/tools/txtar"
+)
+
+// Reader reads a txtar archive one file at a time, so only the entry being
+// processed is held in memory.
+type Reader struct {
+	r       *bufio.Reader
+	comment []byte
+	next    string
+	started bool
+	err     error
+}
+
+func NewReader(r io.Reader) *Reader {
+	return &Reader{r: bufio.NewReaderSize(r, 64*1024)}
+}
+
+// Comment returns the archive comment. It is only complete once the first
+// call to Next has returned.
+func (r *Reader) Comment() []byte {
+	return r.comment
+}
+
+func (r *Reader) Next() (*txtar.File, error) {
+	if !r.started {
+		r.started = true
+		var name string
+		r.comment, name, r.err = r.readSection()
+		r.next = name
+	}
+
+	if r.next == "" {
+		if r.err != nil {
+			return nil, r.err
+		}
+		return nil, io.EOF
+	}
+
+	file := &txtar.File{Name: r.next}
+	file.Data, r.next, r.err = r.readSection()
+	return file, nil
+}
+
+func (r *Reader) readSection() ([]byte, string, error) {
+	var buf bytes.Buffer
+	for {
+		line, err := r.r.ReadBytes('\n')
+		if len(line) > 0 {
+			if name := markerName(line); name != "" {
+				return buf.Bytes(), name, nil
+			}
+			buf.Write(line)
+		}
+		if err == io.EOF {
+			data := buf.Bytes()
+			if len(data) > 0 && data[len(data)-1] != '\n' {
+				data = append(data, '\n')
+			}
+			return data, "", nil
+		}
+		if err != nil {
+			return buf.Bytes(), "", err
+		}
+	}
+}
+
+// markerName mirrors txtar's marker detection for a single line.
+func markerName(line []byte) string {
+	line = bytes.TrimSuffix(line, []byte("\n"))
+	if !bytes.HasPrefix(line, []byte("-- ")) || !bytes.HasSuffix(line, []byte(" --")) || len(line) < 6 {
+		return ""
+	}
+	return strings.TrimSpace(string(line[3 : len(line)-3]))
+}
+
+// OpenArchive opens path, or stdin for "-", transparently decrypting and
+// decompressing it.
+func OpenArchive(path string) (io.ReadCloser, error) {
+	var f io.ReadCloser = io.NopCloser(os.Stdin)
+	if path != "-" {
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		f = file
+	}
+
+	r, err := decodeReader(f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	return struct {
+		io.Reader
+		io.Closer
+	}{r, closerFunc(func() error {
+		r.Close()
+		return f.Close()
+	})}, nil
+}
+
+type closerFunc func() error
+
+func (c closerFunc) Close() error { return c() }
+
+func ReadArchiveData(path string) ([]byte, error) {
+	rc, err := OpenArchive(path)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	return io.ReadAll(rc)
+}
+
+func ReadArchive(path string) (*txtar.Archive, error) {
+	data, err := ReadArchiveData(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return txtar.Parse(data), nil
+}
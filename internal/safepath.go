@@ -0,0 +1,141 @@
+package internal
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"unicode"
+
+	"github.com/bmatcuk/doublestar/v4"
+)
+
+// DefaultDenyPatterns are entry names unpack refuses to write unless the
+// user overrides the deny-list. Writing into .git can install hooks that run
+// on the next git command, in the top-level repository or a nested one.
+var DefaultDenyPatterns = []string{"**/.git", "**/.git/**"}
+
+// deniedBy returns the first deny pattern matching name, or "".
+func deniedBy(name string, patterns []string, fold bool) string {
+	if fold {
+		name = strings.ToLower(name)
+	}
+	for _, pattern := range patterns {
+		if fold {
+			pattern = strings.ToLower(pattern)
+		}
+		if ok, _ := doublestar.Match(pattern, name); ok {
+			return pattern
+		}
+	}
+	return ""
+}
+
+// caseFolding decides whether names that differ only in case refer to the
+// same file in dir. mode is "on", "off", or "auto" (probe the file system).
+func caseFolding(dir, mode string) (bool, error) {
+	switch mode {
+	case "on":
+		return true, nil
+	case "off":
+		return false, nil
+	case "", "auto":
+	default:
+		return false, fmt.Errorf("unknown case folding mode %q (want auto, on, or off)", mode)
+	}
+
+	// Probe without writing anything, which matters for dry runs: look up
+	// an existing entry with its case flipped.
+	entries, err := os.ReadDir(dir)
+	if err == nil {
+		for _, e := range entries {
+			flipped := flipCase(e.Name())
+			if flipped == e.Name() {
+				continue
+			}
+
+			orig, err := os.Lstat(filepath.Join(dir, e.Name()))
+			if err != nil {
+				continue
+			}
+			other, err := os.Lstat(filepath.Join(dir, flipped))
+			return err == nil && os.SameFile(orig, other), nil
+		}
+	}
+
+	// The directory is missing or has no name with letters; guess from the OS.
+	return runtime.GOOS == "windows" || runtime.GOOS == "darwin", nil
+}
+
+// flipCase swaps upper and lower case letters in s.
+func flipCase(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsUpper(r) {
+			return unicode.ToLower(r)
+		}
+		return unicode.ToUpper(r)
+	}, s)
+}
+
+// confine checks rel against root on the real file system. Symlinks among
+// the parents must resolve inside root, and the target itself must not be a
+// symlink, so a write to filepath.Join(root, rel) cannot escape root.
+func confine(root, rel string) error {
+	realRoot, err := filepath.EvalSymlinks(root)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if realRoot, err = filepath.Abs(realRoot); err != nil {
+		return err
+	}
+
+	current := realRoot
+	parts := strings.Split(rel, string(filepath.Separator))
+	for i, part := range parts {
+		next := filepath.Join(current, part)
+		info, err := os.Lstat(next)
+		if os.IsNotExist(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if info.Mode()&os.ModeSymlink == 0 {
+			if !info.IsDir() {
+				// Nothing can be created below a file; unpack reports
+				// the conflict.
+				return nil
+			}
+			current = next
+			continue
+		}
+
+		if i == len(parts)-1 {
+			return fmt.Errorf("target is a symlink")
+		}
+
+		resolved, err := filepath.EvalSymlinks(next)
+		if err != nil {
+			return fmt.Errorf("cannot resolve symlink %s: %w", next, err)
+		}
+		if !withinDir(realRoot, resolved) {
+			return fmt.Errorf("symlink %s points outside the output directory", next)
+		}
+		current = resolved
+	}
+
+	return nil
+}
+
+func withinDir(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	if err != nil {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) && !filepath.IsAbs(rel)
+}
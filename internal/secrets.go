@@ -0,0 +1,209 @@
+package internal
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// SecretRule flags matches of Pattern. If the pattern has a group named
+// "secret", only that group is reported and redacted.
+type SecretRule struct {
+	Name    string
+	Pattern *regexp.Regexp
+
+	// plausible, if set, filters out matches that are clearly not secrets.
+	plausible func(string) bool
+}
+
+type SecretFinding struct {
+	File  string
+	Line  int
+	Rule  string
+	start int
+	end   int
+}
+
+var defaultSecretRules = []SecretRule{
+	{Name: "aws-access-key-id", Pattern: regexp.MustCompile(`\b(?P<secret>(?:AKIA|ASIA)[0-9A-Z]{16})\b`)},
+	{Name: "aws-secret-access-key", Pattern: regexp.MustCompile(`(?i)aws.{0,20}?(?:secret|private).{0,20}?[=:]\s*["']?(?P<secret>[A-Za-z0-9/+=]{40})\b`)},
+	{Name: "private-key", Pattern: regexp.MustCompile(`-----BEGIN [A-Z0-9 ]*PRIVATE KEY-----\r?\n(?P<secret>[\s\S]*?)\r?\n?-----END [A-Z0-9 ]*PRIVATE KEY-----`)},
+	{Name: "github-token", Pattern: regexp.MustCompile(`\b(?P<secret>(?:ghp|gho|ghu|ghs|ghr)_[A-Za-z0-9]{36,}|github_pat_[A-Za-z0-9_]{22,})\b`)},
+	{Name: "secret-assignment", plausible: notPlaceholder, Pattern: regexp.MustCompile(`(?i)\b[a-z0-9_]*(?:secret|token|passwd|password|api_?key|access_?key)[a-z0-9_]*["']?\s*[:=]\s*["'](?P<secret>[^\s"']{8,})["']`)},
+	{Name: "secret-assignment", plausible: notPlaceholder, Pattern: regexp.MustCompile(`(?im)^[ \t]*(?:export[ \t]+)?[a-z0-9_.-]*(?:secret|token|passwd|password|api_?key|access_?key)[a-z0-9_.-]*[ \t]*[:=][ \t]*(?P<secret>[A-Za-z0-9_+/=.~-]{8,})[ \t]*\r?$`)},
+}
+
+var (
+	dotenvLine   = regexp.MustCompile(`(?m)^[ \t]*(?:export[ \t]+)?[A-Za-z_][A-Za-z0-9_]*[ \t]*=[ \t]*(?P<secret>[^\s#].*?)[ \t]*\r?$`)
+	quotedString = regexp.MustCompile("[\"'`](?P<secret>[A-Za-z0-9+/=_-]{20,})[\"'`]")
+)
+
+// entropyMinimum is the bits per byte above which a long quoted string
+// looks random enough to be a key.
+const entropyMinimum = 4.5
+
+// entropyThreshold returns the entropy a quoted string of n bytes needs to
+// look random. A string of n bytes has at most log2(n) bits per byte, so
+// short strings are held to 90% of that instead of entropyMinimum; random
+// keys of 20 bytes usually reach it and identifiers do not.
+func entropyThreshold(n int) float64 {
+	return math.Min(entropyMinimum, 0.9*math.Log2(float64(n)))
+}
+
+// CompileSecretRule builds a rule from a user-supplied regular expression.
+func CompileSecretRule(name, pattern string) (SecretRule, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return SecretRule{}, fmt.Errorf("invalid secret rule %q: %w", name, err)
+	}
+	return SecretRule{Name: name, Pattern: re}, nil
+}
+
+func isDotenv(name string) bool {
+	base := path.Base(filepath.ToSlash(name))
+	return base == ".env" || strings.HasPrefix(base, ".env.") || strings.HasSuffix(base, ".env")
+}
+
+// notPlaceholder rejects identifier-like values such as "password" or
+// "ssh-password" that name a secret rather than hold one.
+func notPlaceholder(value string) bool {
+	return strings.Trim(value, "abcdefghijklmnopqrstuvwxyz-_.") != ""
+}
+
+// mixedCase reports whether s has upper and lower case letters and digits,
+// as random keys do and words or hex digests do not.
+func mixedCase(s string) bool {
+	return strings.ContainsAny(s, "ABCDEFGHIJKLMNOPQRSTUVWXYZ") &&
+		strings.ContainsAny(s, "abcdefghijklmnopqrstuvwxyz") &&
+		strings.ContainsAny(s, "0123456789")
+}
+
+// shannonEntropy returns the entropy of s in bits per byte.
+func shannonEntropy(s string) float64 {
+	var counts [256]int
+	for i := 0; i < len(s); i++ {
+		counts[s[i]]++
+	}
+
+	var entropy float64
+	for _, c := range counts {
+		if c == 0 {
+			continue
+		}
+		p := float64(c) / float64(len(s))
+		entropy -= p * math.Log2(p)
+	}
+	return entropy
+}
+
+func secretSpan(re *regexp.Regexp, m []int) (int, int) {
+	if i := re.SubexpIndex("secret"); i > 0 && m[2*i] >= 0 {
+		return m[2*i], m[2*i+1]
+	}
+	return m[0], m[1]
+}
+
+// ScanSecrets reports likely secrets in data. Overlapping matches are
+// reported once, by the first rule that found them.
+func ScanSecrets(name string, data []byte, extra []SecretRule) []SecretFinding {
+	if isBinaryContent(data) {
+		return nil
+	}
+
+	var findings []SecretFinding
+	add := func(rule string, start, end int) {
+		findings = append(findings, SecretFinding{File: name, Rule: rule, start: start, end: end})
+	}
+
+	for _, rule := range append(append([]SecretRule{}, defaultSecretRules...), extra...) {
+		for _, m := range rule.Pattern.FindAllSubmatchIndex(data, -1) {
+			start, end := secretSpan(rule.Pattern, m)
+			if rule.plausible == nil || rule.plausible(string(data[start:end])) {
+				add(rule.Name, start, end)
+			}
+		}
+	}
+
+	if isDotenv(name) {
+		for _, m := range dotenvLine.FindAllSubmatchIndex(data, -1) {
+			start, end := secretSpan(dotenvLine, m)
+			add("dotenv", start, end)
+		}
+	}
+
+	for _, m := range quotedString.FindAllSubmatchIndex(data, -1) {
+		start, end := secretSpan(quotedString, m)
+		if value := string(data[start:end]); mixedCase(value) && shannonEntropy(value) >= entropyThreshold(len(value)) {
+			add("high-entropy", start, end)
+		}
+	}
+
+	sort.SliceStable(findings, func(i, j int) bool {
+		return findings[i].start < findings[j].start
+	})
+
+	var result []SecretFinding
+	lastEnd := -1
+	for _, f := range findings {
+		if f.start < lastEnd || f.start == f.end {
+			continue
+		}
+		f.Line = 1 + strings.Count(string(data[:f.start]), "\n")
+		result = append(result, f)
+		lastEnd = f.end
+	}
+	return result
+}
+
+// RedactSecrets replaces each finding with a placeholder naming its rule.
+func RedactSecrets(data []byte, findings []SecretFinding) []byte {
+	var out []byte
+	prev := 0
+	for _, f := range findings {
+		out = append(out, data[prev:f.start]...)
+		out = append(out, fmt.Sprintf("[REDACTED:%s]", f.Rule)...)
+		prev = f.end
+	}
+	return append(out, data[prev:]...)
+}
+
+// applySecretPolicy scans the packed files and warns, fails, or redacts
+// according to mode.
+func applySecretPolicy(files []string, contents map[string][]byte, opts PackOptions) error {
+	mode := opts.Secrets
+	switch mode {
+	case "off":
+		return nil
+	case "", "warn", "fail", "redact":
+	default:
+		return fmt.Errorf("unknown secrets mode %q (want off, warn, fail, or redact)", mode)
+	}
+
+	total := 0
+	for _, file := range files {
+		findings := ScanSecrets(file, contents[file], opts.SecretRules)
+		if len(findings) == 0 {
+			continue
+		}
+		total += len(findings)
+
+		label := "Possible secret"
+		if mode == "redact" {
+			label = "Redacted"
+			contents[file] = RedactSecrets(contents[file], findings)
+		}
+		for _, f := range findings {
+			fmt.Fprintf(os.Stderr, "%s (%s): %s:%d\n", label, f.Rule, filepath.ToSlash(f.File), f.Line)
+		}
+	}
+
+	if mode == "fail" && total > 0 {
+		return fmt.Errorf("found %d possible secrets (use --secrets=redact to mask them)", total)
+	}
+	return nil
+}
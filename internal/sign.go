@@ -0,0 +1,196 @@
+package internal
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/tools/txtar"
+)
+
+const (
+	signatureHeader = "# txtar signature v1"
+	signatureSigner = "# signer "
+	signatureValue  = "# sig "
+
+	// signatureNamespace keeps txtar signatures from being valid for any
+	// other protocol that signs with the same key.
+	signatureNamespace = "txtar-signature-v1\n"
+)
+
+// LoadSigner reads an ed25519 PKCS#8 PEM key or any OpenSSH private key.
+// Encrypted OpenSSH keys use the TXTAR_KEY_PASSPHRASE environment variable.
+func LoadSigner(path string) (ssh.Signer, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	signer, err := ssh.ParsePrivateKey(data)
+	var missing *ssh.PassphraseMissingError
+	if errors.As(err, &missing) {
+		passphrase := os.Getenv("TXTAR_KEY_PASSPHRASE")
+		if passphrase == "" {
+			return nil, fmt.Errorf("key %s is encrypted; set TXTAR_KEY_PASSPHRASE", path)
+		}
+		signer, err = ssh.ParsePrivateKeyWithPassphrase(data, []byte(passphrase))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse private key %s: %w", path, err)
+	}
+
+	return signer, nil
+}
+
+// LoadPublicKeys reads PEM public keys or authorized_keys style lines
+// ("ssh-ed25519 AAAA... comment"). Several keys may be listed in one file.
+func LoadPublicKeys(path string) ([]ssh.PublicKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var keys []ssh.PublicKey
+	if block, _ := pem.Decode(data); block != nil {
+		pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse public key %s: %w", path, err)
+		}
+		key, err := ssh.NewPublicKey(pub)
+		if err != nil {
+			return nil, fmt.Errorf("unsupported public key %s: %w", path, err)
+		}
+		return []ssh.PublicKey{key}, nil
+	}
+
+	rest := data
+	for len(bytes.TrimSpace(rest)) > 0 {
+		key, _, _, r, err := ssh.ParseAuthorizedKey(rest)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse public key %s: %w", path, err)
+		}
+		keys = append(keys, key)
+		rest = r
+	}
+
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("no public keys in %s", path)
+	}
+	return keys, nil
+}
+
+func stripSignature(comment []byte) []byte {
+	var out bytes.Buffer
+	inSignature := false
+	for _, line := range bytes.SplitAfter(comment, []byte("\n")) {
+		text := strings.TrimSuffix(string(line), "\n")
+		if text == signatureHeader {
+			inSignature = true
+			continue
+		}
+		if inSignature && (strings.HasPrefix(text, signatureSigner) || strings.HasPrefix(text, signatureValue)) {
+			continue
+		}
+		inSignature = false
+		out.Write(line)
+	}
+	return fixNL(out.Bytes())
+}
+
+// canonicalArchive is the signed form: the archive without its signature
+// block, serialized with txtar.Format.
+func canonicalArchive(archive *txtar.Archive) []byte {
+	canonical := &txtar.Archive{
+		Comment: stripSignature(archive.Comment),
+		Files:   archive.Files,
+	}
+	return append([]byte(signatureNamespace), txtar.Format(canonical)...)
+}
+
+func SignArchive(archive *txtar.Archive, signer ssh.Signer) error {
+	data := canonicalArchive(archive)
+
+	var sig *ssh.Signature
+	var err error
+	if as, ok := signer.(ssh.AlgorithmSigner); ok && signer.PublicKey().Type() == ssh.KeyAlgoRSA {
+		sig, err = as.SignWithAlgorithm(rand.Reader, data, ssh.KeyAlgoRSASHA512)
+	} else {
+		sig, err = signer.Sign(rand.Reader, data)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to sign archive: %w", err)
+	}
+
+	var comment bytes.Buffer
+	comment.Write(stripSignature(archive.Comment))
+	fmt.Fprintln(&comment, signatureHeader)
+	fmt.Fprintf(&comment, "%s%s", signatureSigner, ssh.MarshalAuthorizedKey(signer.PublicKey()))
+	fmt.Fprintf(&comment, "%s%s %s\n", signatureValue, sig.Format, base64.StdEncoding.EncodeToString(sig.Blob))
+
+	archive.Comment = comment.Bytes()
+	return nil
+}
+
+// VerifySignature checks the archive signature and that it was made by one
+// of trusted. It returns the key that signed the archive.
+func VerifySignature(archive *txtar.Archive, trusted []ssh.PublicKey) (ssh.PublicKey, error) {
+	var signerLine, sigLine string
+	scanner := bufio.NewScanner(bytes.NewReader(archive.Comment))
+	inSignature := false
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == signatureHeader:
+			inSignature = true
+		case inSignature && strings.HasPrefix(line, signatureSigner):
+			signerLine = strings.TrimPrefix(line, signatureSigner)
+		case inSignature && strings.HasPrefix(line, signatureValue):
+			sigLine = strings.TrimPrefix(line, signatureValue)
+		default:
+			inSignature = false
+		}
+	}
+
+	if signerLine == "" || sigLine == "" {
+		return nil, fmt.Errorf("archive is not signed")
+	}
+
+	key, _, _, _, err := ssh.ParseAuthorizedKey([]byte(signerLine))
+	if err != nil {
+		return nil, fmt.Errorf("malformed signer key: %w", err)
+	}
+
+	format, blob, ok := strings.Cut(sigLine, " ")
+	if !ok {
+		return nil, fmt.Errorf("malformed signature")
+	}
+	rawSig, err := base64.StdEncoding.DecodeString(blob)
+	if err != nil {
+		return nil, fmt.Errorf("malformed signature: %w", err)
+	}
+
+	isTrusted := false
+	for _, t := range trusted {
+		if bytes.Equal(t.Marshal(), key.Marshal()) {
+			isTrusted = true
+			break
+		}
+	}
+	if !isTrusted {
+		return nil, fmt.Errorf("archive was signed by an untrusted key %s", ssh.FingerprintSHA256(key))
+	}
+
+	if err := key.Verify(canonicalArchive(archive), &ssh.Signature{Format: format, Blob: rawSig}); err != nil {
+		return nil, fmt.Errorf("bad signature: %w", err)
+	}
+
+	return key, nil
+}
@@ -0,0 +1,88 @@
+package internal
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/tools/txtar"
+)
+
+func writeTestKeys(t *testing.T) (privPath, pubPath string) {
+	t.Helper()
+
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey failed: %v", err)
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(priv)
+	if err != nil {
+		t.Fatalf("MarshalPKCS8PrivateKey failed: %v", err)
+	}
+
+	sshPub, err := ssh.NewPublicKey(pub)
+	if err != nil {
+		t.Fatalf("NewPublicKey failed: %v", err)
+	}
+
+	dir := t.TempDir()
+	privPath = filepath.Join(dir, "key.pem")
+	pubPath = filepath.Join(dir, "key.pub")
+	os.WriteFile(privPath, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0600)
+	os.WriteFile(pubPath, ssh.MarshalAuthorizedKey(sshPub), 0644)
+	return privPath, pubPath
+}
+
+func TestSignAndVerify(t *testing.T) {
+	privPath, pubPath := writeTestKeys(t)
+
+	signer, err := LoadSigner(privPath)
+	if err != nil {
+		t.Fatalf("LoadSigner failed: %v", err)
+	}
+	keys, err := LoadPublicKeys(pubPath)
+	if err != nil {
+		t.Fatalf("LoadPublicKeys failed: %v", err)
+	}
+
+	archive := txtar.Parse([]byte("notes\n-- a.txt --\na\n"))
+	if err := SignArchive(archive, signer); err != nil {
+		t.Fatalf("SignArchive failed: %v", err)
+	}
+
+	// Re-signing replaces the old signature instead of stacking another.
+	if err := SignArchive(archive, signer); err != nil {
+		t.Fatalf("SignArchive failed: %v", err)
+	}
+
+	signed := txtar.Parse(txtar.Format(archive))
+	if _, err := VerifySignature(signed, keys); err != nil {
+		t.Fatalf("VerifySignature failed: %v", err)
+	}
+
+	signed.Files[0].Data = []byte("b\n")
+	if _, err := VerifySignature(signed, keys); err == nil {
+		t.Error("Expected tampered archive to fail verification")
+	}
+
+	_, otherPub := writeTestKeys(t)
+	otherKeys, _ := LoadPublicKeys(otherPub)
+	if _, err := VerifySignature(txtar.Parse(txtar.Format(archive)), otherKeys); err == nil {
+		t.Error("Expected untrusted key to fail verification")
+	}
+
+	err = Unpack(&txtar.Archive{Files: []txtar.File{{Name: "a.txt"}}}, UnpackOptions{
+		Dir:              t.TempDir(),
+		RequireSignature: true,
+		TrustedKeys:      keys,
+	})
+	if err == nil {
+		t.Error("Expected unsigned archive to be rejected")
+	}
+}
@@ -0,0 +1,226 @@
+package internal
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+
+	"golang.org/x/tools/txtar"
+)
+
+type SplitOptions struct {
+	MaxBytes        int64
+	MaxFiles        int
+	MaxTokens       int
+	AllowSplitFiles bool
+	Tokenizer       Tokenizer
+}
+
+type splitItem struct {
+	file   txtar.File
+	bytes  int64
+	tokens int
+}
+
+var splitNameRe = regexp.MustCompile(`^(.+) \[split (\d+)/(\d+)\]$`)
+
+func splitName(name string, i, n int) string {
+	return fmt.Sprintf("%s [split %d/%d]", name, i, n)
+}
+
+func entryCost(tok Tokenizer, name string, data []byte) (int64, int) {
+	marker := fmt.Sprintf("-- %s --\n", name)
+	data = fixNL(data)
+	return int64(len(marker) + len(data)), tok.CountTokens([]byte(marker)) + tok.CountTokens(data)
+}
+
+func (o SplitOptions) fits(bytes int64, tokens, files int) bool {
+	if o.MaxBytes > 0 && bytes > o.MaxBytes {
+		return false
+	}
+	if o.MaxTokens > 0 && tokens > o.MaxTokens {
+		return false
+	}
+	if o.MaxFiles > 0 && files > o.MaxFiles {
+		return false
+	}
+	return true
+}
+
+// Split distributes the files of archive over numbered parts that each stay
+// within the configured limits. A file that exceeds the limits on its own is
+// placed alone in a part, unless AllowSplitFiles cuts it into line-aligned
+// chunks that JoinSplitFiles can reassemble.
+func Split(archive *txtar.Archive, opts SplitOptions) ([]*txtar.Archive, []string, error) {
+	if opts.MaxBytes <= 0 && opts.MaxFiles <= 0 && opts.MaxTokens <= 0 {
+		return nil, nil, fmt.Errorf("one of --max-bytes, --max-files, or --max-tokens is required")
+	}
+	if opts.Tokenizer == nil {
+		opts.Tokenizer = BPETokenizer{}
+	}
+
+	var items []splitItem
+	var warnings []string
+
+	for _, f := range archive.Files {
+		b, t := entryCost(opts.Tokenizer, f.Name, f.Data)
+		if opts.fits(b, t, 1) {
+			items = append(items, splitItem{file: f, bytes: b, tokens: t})
+			continue
+		}
+
+		if !opts.AllowSplitFiles {
+			warnings = append(warnings, fmt.Sprintf("%s exceeds the part limit and is placed in its own part", f.Name))
+			items = append(items, splitItem{file: f, bytes: b, tokens: t})
+			continue
+		}
+
+		chunks, err := chunkLines(f.Name, f.Data, opts)
+		if err != nil {
+			return nil, nil, err
+		}
+		for i, chunk := range chunks {
+			name := splitName(f.Name, i+1, len(chunks))
+			b, t := entryCost(opts.Tokenizer, name, chunk)
+			if !opts.fits(b, t, 1) {
+				warnings = append(warnings, fmt.Sprintf("%s has a line longer than the part limit", name))
+			}
+			items = append(items, splitItem{file: txtar.File{Name: name, Data: chunk}, bytes: b, tokens: t})
+		}
+	}
+
+	var groups [][]splitItem
+	var current []splitItem
+	var curBytes int64
+	var curTokens int
+
+	for _, item := range items {
+		if len(current) > 0 && !opts.fits(curBytes+item.bytes, curTokens+item.tokens, len(current)+1) {
+			groups = append(groups, current)
+			current, curBytes, curTokens = nil, 0, 0
+		}
+		current = append(current, item)
+		curBytes += item.bytes
+		curTokens += item.tokens
+	}
+	if len(current) > 0 {
+		groups = append(groups, current)
+	}
+
+	var parts []*txtar.Archive
+	for i, group := range groups {
+		var comment bytes.Buffer
+		fmt.Fprintf(&comment, "part %d/%d, files %s..%s\n", i+1, len(groups), group[0].file.Name, group[len(group)-1].file.Name)
+		if i == 0 {
+			comment.Write(fixNL(archive.Comment))
+		}
+
+		part := &txtar.Archive{Comment: comment.Bytes()}
+		for _, item := range group {
+			part.Files = append(part.Files, item.file)
+		}
+		parts = append(parts, part)
+	}
+
+	return parts, warnings, nil
+}
+
+func chunkLines(name string, data []byte, opts SplitOptions) ([][]byte, error) {
+	var chunks [][]byte
+	var current []byte
+
+	// Reserve room for the longest marker this file can get.
+	overheadBytes, overheadTokens := entryCost(opts.Tokenizer, splitName(name, 9999, 9999), nil)
+	limits := opts
+	limits.MaxFiles = 0
+	// A limit of zero or less means no limit to fits, so a part that cannot
+	// hold the marker itself is an error rather than an unbounded chunk.
+	if limits.MaxBytes > 0 {
+		if limits.MaxBytes <= overheadBytes {
+			return nil, fmt.Errorf("cannot split %s: --max-bytes %d leaves no room after its %d-byte split marker", name, opts.MaxBytes, overheadBytes)
+		}
+		limits.MaxBytes -= overheadBytes
+	}
+	if limits.MaxTokens > 0 {
+		if limits.MaxTokens <= overheadTokens {
+			return nil, fmt.Errorf("cannot split %s: --max-tokens %d leaves no room after its %d-token split marker", name, opts.MaxTokens, overheadTokens)
+		}
+		limits.MaxTokens -= overheadTokens
+	}
+
+	var curTokens int
+	for len(data) > 0 {
+		line := data
+		if i := bytes.IndexByte(data, '\n'); i >= 0 {
+			line = data[:i+1]
+		}
+		data = data[len(line):]
+
+		lineTokens := opts.Tokenizer.CountTokens(line)
+		if len(current) > 0 && !limits.fits(int64(len(current)+len(line)), curTokens+lineTokens, 0) {
+			chunks = append(chunks, current)
+			current, curTokens = nil, 0
+		}
+		current = append(current, line...)
+		curTokens += lineTokens
+	}
+	if len(current) > 0 {
+		chunks = append(chunks, current)
+	}
+
+	return chunks, nil
+}
+
+// JoinSplitFiles reassembles entries produced by Split with AllowSplitFiles.
+// The joined file takes the position of its first chunk.
+func JoinSplitFiles(archive *txtar.Archive) (*txtar.Archive, error) {
+	type pending struct {
+		index  int
+		total  int
+		chunks map[int][]byte
+	}
+
+	joined := &txtar.Archive{Comment: archive.Comment}
+	open := make(map[string]*pending)
+	var order []string
+
+	for _, f := range archive.Files {
+		m := splitNameRe.FindStringSubmatch(f.Name)
+		if m == nil {
+			joined.Files = append(joined.Files, f)
+			continue
+		}
+
+		name := m[1]
+		n, _ := strconv.Atoi(m[2])
+		total, _ := strconv.Atoi(m[3])
+
+		p, ok := open[name]
+		if !ok {
+			p = &pending{index: len(joined.Files), total: total, chunks: make(map[int][]byte)}
+			open[name] = p
+			order = append(order, name)
+			joined.Files = append(joined.Files, txtar.File{Name: name})
+		}
+		if total != p.total || n < 1 || n > total {
+			return nil, fmt.Errorf("inconsistent split entry %q", f.Name)
+		}
+		p.chunks[n] = f.Data
+	}
+
+	for _, name := range order {
+		p := open[name]
+		var data []byte
+		for i := 1; i <= p.total; i++ {
+			chunk, ok := p.chunks[i]
+			if !ok {
+				return nil, fmt.Errorf("incomplete split file %q: missing part %d/%d (merge all parts first)", name, i, p.total)
+			}
+			data = append(data, chunk...)
+		}
+		joined.Files[p.index].Data = data
+	}
+
+	return joined, nil
+}
@@ -0,0 +1,84 @@
+package internal
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"golang.org/x/tools/txtar"
+)
+
+func TestSplitByFiles(t *testing.T) {
+	archive := txtar.Parse([]byte("orig\n-- a --\na\n-- b --\nb\n-- c --\nc\n"))
+
+	parts, _, err := Split(archive, SplitOptions{MaxFiles: 2})
+	if err != nil {
+		t.Fatalf("Split failed: %v", err)
+	}
+
+	if len(parts) != 2 {
+		t.Fatalf("Expected 2 parts, got %d", len(parts))
+	}
+
+	if string(parts[0].Comment) != "part 1/2, files a..b\norig\n" {
+		t.Errorf("Unexpected first comment: %q", parts[0].Comment)
+	}
+	if string(parts[1].Comment) != "part 2/2, files c..c\n" {
+		t.Errorf("Unexpected second comment: %q", parts[1].Comment)
+	}
+}
+
+func TestSplitFilesRoundTrip(t *testing.T) {
+	big := strings.Repeat("some line of text\n", 50)
+	archive := &txtar.Archive{Files: []txtar.File{
+		{Name: "small.txt", Data: []byte("small\n")},
+		{Name: "big.txt", Data: []byte(big)},
+	}}
+
+	parts, warnings, err := Split(archive, SplitOptions{MaxBytes: 200})
+	if err != nil {
+		t.Fatalf("Split failed: %v", err)
+	}
+	if len(warnings) != 1 || len(parts) != 2 {
+		t.Errorf("Expected oversized file warning and 2 parts, got %v, %d parts", warnings, len(parts))
+	}
+
+	parts, _, err = Split(archive, SplitOptions{MaxBytes: 200, AllowSplitFiles: true})
+	if err != nil {
+		t.Fatalf("Split failed: %v", err)
+	}
+
+	var inputs []MergeInput
+	for _, p := range parts {
+		if n := len(txtar.Format(&txtar.Archive{Files: p.Files})); n > 200 {
+			t.Errorf("Part exceeds limit: %d bytes", n)
+		}
+		inputs = append(inputs, MergeInput{Archive: p})
+	}
+
+	merged, err := Merge(inputs, MergeOptions{})
+	if err != nil {
+		t.Fatalf("Merge failed: %v", err)
+	}
+
+	if len(merged.Files) != 2 || !bytes.Equal(merged.Files[1].Data, []byte(big)) {
+		t.Errorf("Split file was not reassembled")
+	}
+
+	if _, err := JoinSplitFiles(parts[1]); err == nil {
+		t.Error("Expected error for incomplete split file")
+	}
+}
+
+func TestSplitFilesTinyLimit(t *testing.T) {
+	archive := &txtar.Archive{Files: []txtar.File{
+		{Name: "docs/a-rather-long-file-name.txt", Data: []byte(strings.Repeat("some line of text\n", 50))},
+	}}
+
+	if _, _, err := Split(archive, SplitOptions{MaxBytes: 40, AllowSplitFiles: true}); err == nil {
+		t.Error("Expected error when the limit cannot hold the split marker")
+	}
+	if _, _, err := Split(archive, SplitOptions{MaxTokens: 3, AllowSplitFiles: true}); err == nil {
+		t.Error("Expected error when the token limit cannot hold the split marker")
+	}
+}
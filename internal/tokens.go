@@ -0,0 +1,110 @@
+package internal
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+type Tokenizer interface {
+	CountTokens(data []byte) int
+}
+
+// CharTokenizer approximates the token count at four bytes per token.
+type CharTokenizer struct{}
+
+func (CharTokenizer) CountTokens(data []byte) int {
+	return (len(data) + 3) / 4
+}
+
+// BPETokenizer approximates byte-pair-encoding tokenizers such as cl100k
+// without a vocabulary: text is pre-split the way those tokenizers do it and
+// each piece is charged by its class and length.
+type BPETokenizer struct{}
+
+func (BPETokenizer) CountTokens(data []byte) int {
+	if !utf8.Valid(data) {
+		return CharTokenizer{}.CountTokens(data)
+	}
+
+	tokens := 0
+	s := string(data)
+	for len(s) > 0 {
+		r, size := utf8.DecodeRuneInString(s)
+		n := 0
+		switch {
+		case unicode.IsLetter(r):
+			n = runLength(s, unicode.IsLetter)
+			tokens += (utf8.RuneCountInString(s[:n]) + 3) / 4
+		case unicode.IsDigit(r):
+			n = runLength(s, unicode.IsDigit)
+			tokens += (n + 2) / 3
+		case r == ' ':
+			n = runLength(s, func(r rune) bool { return r == ' ' })
+			// A single space is merged into the following word.
+			if n > 1 || n == len(s) {
+				tokens++
+			}
+		case unicode.IsSpace(r):
+			n = runLength(s, unicode.IsSpace)
+			tokens++
+		default:
+			n = size
+			tokens++
+		}
+		s = s[n:]
+	}
+	return tokens
+}
+
+func runLength(s string, fn func(rune) bool) int {
+	for i, r := range s {
+		if !fn(r) {
+			return i
+		}
+	}
+	return len(s)
+}
+
+func NewTokenizer(name string) (Tokenizer, error) {
+	switch name {
+	case "", "bpe":
+		return BPETokenizer{}, nil
+	case "chars":
+		return CharTokenizer{}, nil
+	default:
+		return nil, fmt.Errorf("unknown tokenizer %q (want bpe or chars)", name)
+	}
+}
+
+// ParseSize parses a byte count such as "512", "200k", "10M" or "1g".
+// Suffixes are powers of 1024.
+func ParseSize(size string) (int64, error) {
+	s := strings.TrimSpace(strings.ToLower(size))
+	s = strings.TrimSuffix(s, "b")
+	if s == "" {
+		return 0, fmt.Errorf("empty size")
+	}
+
+	mult := int64(1)
+	switch s[len(s)-1] {
+	case 'k':
+		mult = 1 << 10
+	case 'm':
+		mult = 1 << 20
+	case 'g':
+		mult = 1 << 30
+	}
+	if mult > 1 {
+		s = s[:len(s)-1]
+	}
+
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size %q", size)
+	}
+
+	return n * mult, nil
+}
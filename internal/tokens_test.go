@@ -0,0 +1,33 @@
+package internal
+
+import "testing"
+
+func TestTokenizers(t *testing.T) {
+	if n := (CharTokenizer{}).CountTokens([]byte("abcdefgh")); n != 2 {
+		t.Errorf("Expected 2 char tokens, got %d", n)
+	}
+
+	// "func", " main", "()", " {", "\n", ... roughly one token per piece.
+	n := BPETokenizer{}.CountTokens([]byte("func main() {\n\treturn 12345\n}\n"))
+	if n < 8 || n > 16 {
+		t.Errorf("Unexpected BPE estimate: %d", n)
+	}
+
+	if _, err := NewTokenizer("nope"); err == nil {
+		t.Error("Expected error for unknown tokenizer")
+	}
+}
+
+func TestParseSize(t *testing.T) {
+	tests := map[string]int64{"512": 512, "200k": 200 << 10, "1M": 1 << 20, "2gb": 2 << 30}
+	for in, want := range tests {
+		got, err := ParseSize(in)
+		if err != nil || got != want {
+			t.Errorf("ParseSize(%q) = %d, %v; want %d", in, got, err, want)
+		}
+	}
+
+	if _, err := ParseSize("lots"); err == nil {
+		t.Error("Expected error for invalid size")
+	}
+}
@@ -0,0 +1,464 @@
+package internal
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/bmatcuk/doublestar/v4"
+)
+
+// Transforms in the order they are applied to a file.
+var transformNames = []string{"license", "comments", "long-strings", "blank-lines"}
+
+// longStringLimit is the length above which string literals are elided.
+const longStringLimit = 200
+
+// TransformRule applies the named transforms to files matching Glob.
+type TransformRule struct {
+	Glob  string
+	Names []string
+}
+
+// ParseTransforms parses "GLOB=NAME[,NAME...]" specs. A spec without "="
+// applies to every file.
+func ParseTransforms(specs []string) ([]TransformRule, error) {
+	var rules []TransformRule
+	for _, spec := range specs {
+		glob, list := "**", spec
+		if i := strings.LastIndex(spec, "="); i >= 0 {
+			glob, list = spec[:i], spec[i+1:]
+		}
+		if !doublestar.ValidatePattern(glob) {
+			return nil, fmt.Errorf("invalid transform glob %q", glob)
+		}
+
+		rule := TransformRule{Glob: glob}
+		for _, name := range strings.Split(list, ",") {
+			name = strings.TrimSpace(name)
+			if !isTransformName(name) {
+				return nil, fmt.Errorf("unknown transform %q (want %s)", name, strings.Join(transformNames, ", "))
+			}
+			rule.Names = append(rule.Names, name)
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+func isTransformName(name string) bool {
+	for _, n := range transformNames {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// commentSyntax describes enough of a language to find its comments and
+// string literals.
+type commentSyntax struct {
+	line       []string
+	blockStart string
+	blockEnd   string
+	quotes     string
+	raw        string // quotes without backslash escapes
+	triple     bool   // Python-style """ and ''' strings
+
+	// spaced line comments only start at the beginning of a line or after
+	// whitespace, as in shell where $# and ${#x} are not comments.
+	spaced bool
+}
+
+var (
+	cSyntax    = &commentSyntax{line: []string{"//"}, blockStart: "/*", blockEnd: "*/", quotes: "\"'`"}
+	goSyntax   = &commentSyntax{line: []string{"//"}, blockStart: "/*", blockEnd: "*/", quotes: "\"'`", raw: "`"}
+	cssSyntax  = &commentSyntax{blockStart: "/*", blockEnd: "*/", quotes: "\"'"}
+	hashSyntax = &commentSyntax{line: []string{"#"}, quotes: "\"'", triple: true, spaced: true}
+	sqlSyntax  = &commentSyntax{line: []string{"--"}, blockStart: "/*", blockEnd: "*/", quotes: "\"'"}
+	hclSyntax  = &commentSyntax{line: []string{"#", "//"}, blockStart: "/*", blockEnd: "*/", quotes: "\""}
+)
+
+func syntaxFor(name string) *commentSyntax {
+	switch languageFor(name) {
+	case "go":
+		if strings.HasSuffix(name, ".go") {
+			return goSyntax
+		}
+		return cSyntax
+	case "c", "cpp", "java", "javascript", "jsx", "typescript", "tsx", "rust", "csharp", "swift", "kotlin", "scss", "php", "protobuf":
+		return cSyntax
+	case "css":
+		return cssSyntax
+	case "python", "ruby", "bash", "zsh", "yaml", "toml", "makefile", "dockerfile", "powershell":
+		return hashSyntax
+	case "sql":
+		return sqlSyntax
+	case "hcl":
+		return hclSyntax
+	default:
+		return nil
+	}
+}
+
+type segment struct {
+	kind byte // 'c' code, 'm' comment, 's' string
+	text string
+}
+
+// lex splits src into code, comment and string segments.
+func lex(src string, syn *commentSyntax) []segment {
+	var segs []segment
+	codeStart := 0
+	emit := func(kind byte, start, end int) {
+		if codeStart < start {
+			segs = append(segs, segment{'c', src[codeStart:start]})
+		}
+		segs = append(segs, segment{kind, src[start:end]})
+		codeStart = end
+	}
+
+	i := 0
+next:
+	for i < len(src) {
+		rest := src[i:]
+
+		if syn.blockStart != "" && strings.HasPrefix(rest, syn.blockStart) {
+			end := len(src)
+			if j := strings.Index(rest[len(syn.blockStart):], syn.blockEnd); j >= 0 {
+				end = i + len(syn.blockStart) + j + len(syn.blockEnd)
+			}
+			emit('m', i, end)
+			i = end
+			continue
+		}
+
+		for _, l := range syn.line {
+			if strings.HasPrefix(rest, l) && (!syn.spaced || i == 0 || strings.IndexByte(" \t\n", src[i-1]) >= 0) {
+				end := len(src)
+				if j := strings.IndexByte(rest, '\n'); j >= 0 {
+					end = i + j
+				}
+				emit('m', i, end)
+				i = end
+				continue next
+			}
+		}
+
+		c := src[i]
+		if strings.IndexByte(syn.quotes, c) < 0 {
+			i++
+			continue
+		}
+
+		if syn.triple && len(rest) >= 3 && rest[1] == c && rest[2] == c {
+			end := len(src)
+			if j := strings.Index(rest[3:], rest[:3]); j >= 0 {
+				end = i + 3 + j + 3
+			}
+			emit('s', i, end)
+			i = end
+			continue
+		}
+
+		raw := strings.IndexByte(syn.raw, c) >= 0
+		j := i + 1
+		for j < len(src) {
+			if src[j] == '\\' && !raw {
+				j += 2
+				continue
+			}
+			if src[j] == c {
+				j++
+				break
+			}
+			if src[j] == '\n' && c != '`' {
+				break
+			}
+			j++
+		}
+		j = min(j, len(src))
+		emit('s', i, j)
+		i = j
+	}
+
+	if codeStart < len(src) {
+		segs = append(segs, segment{'c', src[codeStart:]})
+	}
+	return segs
+}
+
+// commentMark stands in for removed comments until blank lines are cleaned up.
+const commentMark = "\x00"
+
+// joinWithoutMarks drops lines left empty by removed comments and trims the
+// space before a removed trailing comment.
+func joinWithoutMarks(text string) string {
+	lines := strings.SplitAfter(text, "\n")
+	var b strings.Builder
+	for _, line := range lines {
+		if !strings.Contains(line, commentMark) {
+			b.WriteString(line)
+			continue
+		}
+
+		nl := strings.HasSuffix(line, "\n")
+		line = strings.TrimRight(strings.ReplaceAll(strings.TrimSuffix(line, "\n"), commentMark, ""), " \t")
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		b.WriteString(line)
+		if nl {
+			b.WriteByte('\n')
+		}
+	}
+	return b.String()
+}
+
+var licenseText = regexp.MustCompile(`(?i)copyright|licen[cs]e|spdx-license-identifier`)
+
+// stripLicense removes a leading comment block that mentions a copyright or
+// license, along with the blank lines after it. A shebang line is kept.
+func stripLicense(src string, syn *commentSyntax) string {
+	segs := lex(src, syn)
+
+	first, last := -1, -1
+	for i, s := range segs {
+		switch {
+		case s.kind == 'm' && strings.HasPrefix(s.text, "#!") && i == 0:
+			continue
+		case s.kind == 'm':
+			if first < 0 {
+				first = i
+			}
+			last = i
+			continue
+		case s.kind == 'c' && strings.TrimSpace(s.text) == "":
+			// Whitespace between comments joins them into one block,
+			// unless it contains a blank line.
+			if first < 0 || strings.Count(s.text, "\n") <= 1 {
+				continue
+			}
+		}
+		break
+	}
+	if first < 0 {
+		return src
+	}
+
+	var block strings.Builder
+	for _, s := range segs[first : last+1] {
+		block.WriteString(s.text)
+	}
+	if !licenseText.MatchString(block.String()) {
+		return src
+	}
+
+	var b strings.Builder
+	for _, s := range segs[:first] {
+		b.WriteString(s.text)
+	}
+	rest := segs[last+1:]
+	for len(rest) > 0 && rest[0].kind == 'c' && strings.TrimSpace(rest[0].text) == "" {
+		rest = rest[1:]
+	}
+	if len(rest) > 0 && rest[0].kind == 'c' {
+		rest[0].text = strings.TrimLeft(rest[0].text, " \t\r\n")
+	}
+	for _, s := range rest {
+		b.WriteString(s.text)
+	}
+	return b.String()
+}
+
+func stripComments(src string, syn *commentSyntax) string {
+	var b strings.Builder
+	for i, s := range lex(src, syn) {
+		if s.kind == 'm' && !(i == 0 && strings.HasPrefix(s.text, "#!")) {
+			b.WriteString(commentMark)
+			continue
+		}
+		b.WriteString(s.text)
+	}
+	return joinWithoutMarks(b.String())
+}
+
+// isGoDirective reports comments the compiler or go tool reads.
+func isGoDirective(g *ast.CommentGroup) bool {
+	for _, c := range g.List {
+		if strings.HasPrefix(c.Text, "//go:") || strings.HasPrefix(c.Text, "// +build") ||
+			strings.HasPrefix(c.Text, "//line ") || strings.HasPrefix(c.Text, "//export ") {
+			return true
+		}
+	}
+	return false
+}
+
+// stripGoComments reprints a Go file without comments, keeping directives
+// and cgo preambles. Files that do not parse are returned unchanged.
+func stripGoComments(src string) string {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", src, parser.ParseComments)
+	if err != nil {
+		return src
+	}
+
+	keep := make(map[*ast.CommentGroup]bool)
+	for _, imp := range file.Imports {
+		if imp.Path.Value == `"C"` {
+			for _, decl := range file.Decls {
+				if gen, ok := decl.(*ast.GenDecl); ok && gen.Tok == token.IMPORT && gen.Doc != nil {
+					for _, spec := range gen.Specs {
+						if spec == imp {
+							keep[gen.Doc] = true
+						}
+					}
+				}
+			}
+		}
+	}
+
+	var comments []*ast.CommentGroup
+	for _, g := range file.Comments {
+		if keep[g] || isGoDirective(g) {
+			keep[g] = true
+			comments = append(comments, g)
+		}
+	}
+	file.Comments = comments
+
+	// The printer also emits doc and line comments attached to nodes.
+	drop := func(g **ast.CommentGroup) {
+		if *g != nil && !keep[*g] {
+			*g = nil
+		}
+	}
+	ast.Inspect(file, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.File:
+			drop(&n.Doc)
+		case *ast.GenDecl:
+			drop(&n.Doc)
+		case *ast.FuncDecl:
+			drop(&n.Doc)
+		case *ast.Field:
+			drop(&n.Doc)
+			drop(&n.Comment)
+		case *ast.ImportSpec:
+			drop(&n.Doc)
+			drop(&n.Comment)
+		case *ast.ValueSpec:
+			drop(&n.Doc)
+			drop(&n.Comment)
+		case *ast.TypeSpec:
+			drop(&n.Doc)
+			drop(&n.Comment)
+		}
+		return true
+	})
+
+	var buf bytes.Buffer
+	if err := (&printer.Config{Mode: printer.UseSpaces | printer.TabIndent, Tabwidth: 8}).Fprint(&buf, fset, file); err != nil {
+		return src
+	}
+	return buf.String()
+}
+
+func elideLongStrings(src string, syn *commentSyntax) string {
+	var b strings.Builder
+	for _, s := range lex(src, syn) {
+		q := 1
+		if syn.triple && len(s.text) >= 6 && s.text[1] == s.text[0] && s.text[2] == s.text[0] {
+			q = 3
+		}
+
+		if s.kind != 's' || len(s.text)-2*q <= longStringLimit || s.text[len(s.text)-1] != s.text[0] {
+			b.WriteString(s.text)
+			continue
+		}
+
+		b.WriteString(s.text[:q])
+		fmt.Fprintf(&b, "<elided %d chars>", len(s.text)-2*q)
+		b.WriteString(s.text[len(s.text)-q:])
+	}
+	return b.String()
+}
+
+var blankRun = regexp.MustCompile(`\n([ \t]*\n){2,}`)
+
+func collapseBlankLines(src string) string {
+	return blankRun.ReplaceAllString(src, "\n\n")
+}
+
+func applyTransform(name, file, src string) string {
+	if name == "blank-lines" {
+		return collapseBlankLines(src)
+	}
+
+	syn := syntaxFor(filepath.ToSlash(file))
+	if syn == nil {
+		return src
+	}
+
+	switch name {
+	case "license":
+		return stripLicense(src, syn)
+	case "comments":
+		if syn == goSyntax {
+			return stripGoComments(src)
+		}
+		return stripComments(src, syn)
+	case "long-strings":
+		return elideLongStrings(src, syn)
+	}
+	return src
+}
+
+// applyTransforms rewrites file contents in place and returns a summary of
+// how many files each transform changed, in application order.
+func applyTransforms(files []string, contents map[string][]byte, rules []TransformRule) []string {
+	changed := make(map[string]int)
+
+	for _, f := range files {
+		if isBinaryContent(contents[f]) {
+			continue
+		}
+
+		wanted := make(map[string]bool)
+		for _, rule := range rules {
+			if m, _ := doublestar.Match(rule.Glob, filepath.ToSlash(f)); m {
+				for _, name := range rule.Names {
+					wanted[name] = true
+				}
+			}
+		}
+
+		src := string(contents[f])
+		for _, name := range transformNames {
+			if !wanted[name] {
+				continue
+			}
+			if out := applyTransform(name, f, src); out != src {
+				changed[name]++
+				src = out
+			}
+		}
+		contents[f] = []byte(src)
+	}
+
+	var summary []string
+	for _, name := range transformNames {
+		if n := changed[name]; n > 0 {
+			summary = append(summary, fmt.Sprintf("%s (%d files)", name, n))
+			fmt.Fprintf(os.Stderr, "Transformed (%s): %d files\n", name, n)
+		}
+	}
+	return summary
+}
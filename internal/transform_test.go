@@ -0,0 +1,117 @@
+package internal
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseTransforms(t *testing.T) {
+	rules, err := ParseTransforms([]string{"**/*.{go,js}=comments,license", "blank-lines"})
+	if err != nil {
+		t.Fatalf("ParseTransforms failed: %v", err)
+	}
+	if len(rules) != 2 || rules[0].Glob != "**/*.{go,js}" || len(rules[0].Names) != 2 || rules[1].Glob != "**" {
+		t.Errorf("Unexpected rules: %+v", rules)
+	}
+
+	if _, err := ParseTransforms([]string{"*.go=minify"}); err == nil {
+		t.Error("Expected error for unknown transform")
+	}
+}
+
+func TestStripComments(t *testing.T) {
+	src := "#!/bin/sh\n# setup\necho '# not a comment' # trailing\n\necho done\n"
+	want := "#!/bin/sh\necho '# not a comment'\n\necho done\n"
+	if got := stripComments(src, hashSyntax); got != want {
+		t.Errorf("hash comments:\ngot  %q\nwant %q", got, want)
+	}
+
+	src = "if [ $# -eq 0 ]; then echo ${#arr[@]}; fi # count\nurl: http://x/#frag\n"
+	want = "if [ $# -eq 0 ]; then echo ${#arr[@]}; fi\nurl: http://x/#frag\n"
+	if got := stripComments(src, hashSyntax); got != want {
+		t.Errorf("hash inside words:\ngot  %q\nwant %q", got, want)
+	}
+
+	src = "/* header\n * more */\nint x = 1; // one\nchar *s = \"// kept\";\n"
+	want = "int x = 1;\nchar *s = \"// kept\";\n"
+	if got := stripComments(src, cSyntax); got != want {
+		t.Errorf("C comments:\ngot  %q\nwant %q", got, want)
+	}
+}
+
+func TestStripGoComments(t *testing.T) {
+	src := "//go:build linux\n\n// Package p does things.\npackage p\n\n// F is documented.\nfunc F() string {\n\treturn \"// not a comment\" // trailing\n}\n"
+	got := stripGoComments(src)
+	if strings.Contains(got, "documented") || strings.Contains(got, "trailing") || strings.Contains(got, "Package p") {
+		t.Errorf("Comments not stripped:\n%s", got)
+	}
+	if !strings.Contains(got, "//go:build linux") || !strings.Contains(got, `"// not a comment"`) {
+		t.Errorf("Directive or string lost:\n%s", got)
+	}
+
+	if broken := "package p\nfunc {"; stripGoComments(broken) != broken {
+		t.Error("Unparsable file should be unchanged")
+	}
+}
+
+func TestStripLicense(t *testing.T) {
+	src := "// Copyright 2024 Example Inc.\n// SPDX-License-Identifier: MIT\n\n// Package p does things.\npackage p\n"
+	want := "// Package p does things.\npackage p\n"
+	if got := stripLicense(src, goSyntax); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	src = "#!/usr/bin/env python\n# Licensed under the Apache License\nimport os\n"
+	if got := stripLicense(src, hashSyntax); got != "#!/usr/bin/env python\nimport os\n" {
+		t.Errorf("Shebang not kept: %q", got)
+	}
+
+	src = "// Package p does things.\npackage p\n"
+	if got := stripLicense(src, goSyntax); got != src {
+		t.Errorf("Ordinary doc comment removed: %q", got)
+	}
+}
+
+func TestElideLongStrings(t *testing.T) {
+	long := strings.Repeat("a", 300)
+	src := "x = \"" + long + "\"\ny = 'short'\nz = \"\"\"" + long + "\"\"\"\n"
+	want := "x = \"<elided 300 chars>\"\ny = 'short'\nz = \"\"\"<elided 300 chars>\"\"\"\n"
+	if got := elideLongStrings(src, hashSyntax); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestCollapseBlankLines(t *testing.T) {
+	if got := collapseBlankLines("a\n\n\n  \n\nb\n\nc\n"); got != "a\n\nb\n\nc\n" {
+		t.Errorf("Unexpected result: %q", got)
+	}
+}
+
+func TestPackTransforms(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	os.WriteFile(filepath.Join(tmpDir, "main.go"), []byte("package main\n\n// main runs.\nfunc main() {}\n"), 0644)
+	os.WriteFile(filepath.Join(tmpDir, "notes.txt"), []byte("// kept\n\n\n\nend\n"), 0644)
+
+	rules, _ := ParseTransforms([]string{"*.go=comments", "blank-lines"})
+	archive, _, err := Pack(context.Background(), PackOptions{Dir: tmpDir, Transforms: rules})
+	if err != nil {
+		t.Fatalf("Pack failed: %v", err)
+	}
+
+	for _, f := range archive.Files {
+		if f.Name == "main.go" && strings.Contains(string(f.Data), "main runs") {
+			t.Errorf("Comment not stripped from main.go: %q", f.Data)
+		}
+		if f.Name == "notes.txt" && string(f.Data) != "// kept\n\nend\n" {
+			t.Errorf("Unexpected notes.txt: %q", f.Data)
+		}
+	}
+
+	if got := string(archive.Comment); got != "transforms: comments (1 files), blank-lines (1 files)\n" {
+		t.Errorf("Unexpected comment: %q", got)
+	}
+}
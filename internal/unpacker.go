@@ -1,21 +1,42 @@
 package internal
 
 import (
+	"bytes"
 	"fmt"
+	"io"
 	"os"
+	"path"
 	"path/filepath"
+	"sort"
 	"strings"
 	"time"
 
+	"golang.org/x/crypto/ssh"
 	"golang.org/x/tools/txtar"
 )
 
 type UnpackOptions struct {
-	Archive      string
-	Dir          string
-	Backup       bool
-	DryRun       bool
-	NoOverwrite  bool
+	Archive          string
+	Dir              string
+	Backup           bool
+	DryRun           bool
+	NoOverwrite      bool
+	Verify           bool
+	Force            bool
+	RequireSignature bool
+	TrustedKeys      []ssh.PublicKey
+	Deny             []string
+	CaseFold         string
+	Include          []string
+	Exclude          []string
+	StripComponents  int
+	Prefix           string
+	ShowDiff         bool
+	Interactive      bool
+	Hunks            bool
+	PromptIn         io.Reader
+	PromptOut        io.Writer
+	PostHooks        []string
 }
 
 func Unpack(archive *txtar.Archive, opts UnpackOptions) error {
@@ -23,64 +44,402 @@ func Unpack(archive *txtar.Archive, opts UnpackOptions) error {
 		return fmt.Errorf("--backup and --no-overwrite are mutually exclusive")
 	}
 
+	if opts.Interactive && opts.DryRun {
+		return fmt.Errorf("--interactive cannot be combined with --dry-run")
+	}
+
 	if opts.Dir == "" {
 		opts.Dir = "."
 	}
 
-	for _, file := range archive.Files {
-		normalizedPath := filepath.FromSlash(file.Name)
-		
-		if err := validatePath(normalizedPath); err != nil {
+	if opts.RequireSignature {
+		key, err := VerifySignature(archive, opts.TrustedKeys)
+		if err != nil {
+			return fmt.Errorf("signature check failed: %w", err)
+		}
+		fmt.Fprintf(os.Stderr, "Signature OK: %s\n", ssh.FingerprintSHA256(key))
+	}
+
+	archive, err := JoinSplitFiles(archive)
+	if err != nil {
+		return err
+	}
+
+	if opts.Verify {
+		problems, err := VerifyManifest(archive)
+		if err != nil {
+			problems = []string{err.Error()}
+		}
+
+		for _, p := range problems {
+			fmt.Fprintf(os.Stderr, "Verify: %s\n", p)
+		}
+
+		if len(problems) > 0 && !opts.Force {
+			return fmt.Errorf("archive failed verification (use --force to extract anyway)")
+		}
+	}
+
+	files, err := selectEntries(archive.Files, opts)
+	if err != nil {
+		return err
+	}
+
+	fold, err := caseFolding(opts.Dir, opts.CaseFold)
+	if err != nil {
+		return err
+	}
+
+	// Check every entry before writing anything, so a bad entry late in the
+	// archive does not leave a partial extraction behind.
+	seen := make(map[string]string)
+	for _, file := range files {
+		if err := validatePath(filepath.FromSlash(file.Name)); err != nil {
 			return fmt.Errorf("invalid path %q: %w", file.Name, err)
 		}
 
-		targetPath := filepath.Join(opts.Dir, normalizedPath)
+		if pattern := deniedBy(file.Name, opts.Deny, fold); pattern != "" {
+			return fmt.Errorf("refusing to write %q: denied by pattern %q", file.Name, pattern)
+		}
+
+		key := file.Name
+		if fold {
+			key = strings.ToLower(key)
+		}
+		if other, ok := seen[key]; ok {
+			return fmt.Errorf("%q collides with %q in the output directory", file.Name, other)
+		}
+		seen[key] = file.Name
+	}
+
+	var actions []unpackAction
+	counts := make(map[string]int)
+	for _, file := range files {
+		normalizedPath := filepath.FromSlash(file.Name)
+
+		if err := confine(opts.Dir, normalizedPath); err != nil {
+			return fmt.Errorf("unsafe path %q: %w", file.Name, err)
+		}
+
+		action, err := classifyEntry(filepath.Join(opts.Dir, normalizedPath), file.Data, opts)
+		if err != nil {
+			return err
+		}
+		action.Name = file.Name
+		actions = append(actions, action)
+		counts[action.Status]++
+	}
+
+	if opts.DryRun {
+		for _, a := range actions {
+			printAction(os.Stdout, a, opts.ShowDiff)
+		}
+		fmt.Printf("Dry run: %d to create, %d to overwrite, %d unchanged, %d conflicts\n",
+			counts[actionCreate], counts[actionOverwrite], counts[actionUnchanged], counts[actionConflict])
+		return nil
+	}
+
+	for _, a := range actions {
+		if a.Status == actionConflict {
+			return fmt.Errorf("%s: %s", a.Target, a.Reason)
+		}
+	}
+
+	if opts.Interactive {
+		if err := reviewActions(actions, opts); err != nil {
+			return err
+		}
+	}
 
-		if opts.DryRun {
-			fmt.Printf("Would write: %s\n", targetPath)
+	var done []writtenFile
+	var createdDirs []string
+	for _, a := range actions {
+		if a.Status == actionUnchanged || a.Status == actionSkipped {
 			continue
 		}
 
-		if err := os.MkdirAll(filepath.Dir(targetPath), 0755); err != nil {
-			return fmt.Errorf("failed to create directory for %q: %w", targetPath, err)
+		createdDirs = append(createdDirs, missingDirs(filepath.Dir(a.Target))...)
+		w, err := writeAction(a, opts)
+		if err != nil {
+			rollback(done, createdDirs)
+			return err
+		}
+		done = append(done, w)
+	}
+
+	if len(opts.PostHooks) > 0 && len(done) > 0 {
+		names := make([]string, len(done))
+		for i, w := range done {
+			names[i] = w.action.Name
+		}
+
+		if err := RunHooks("unpack.post", opts.PostHooks, opts.Dir, names); err != nil {
+			rollback(done, createdDirs)
+			return err
+		}
+	}
+
+	written := len(done)
+	var notes []string
+	if n := counts[actionUnchanged]; n > 0 {
+		notes = append(notes, fmt.Sprintf("%d unchanged", n))
+	}
+	if skipped := len(actions) - written - counts[actionUnchanged]; skipped > 0 {
+		notes = append(notes, fmt.Sprintf("%d skipped", skipped))
+	}
+	if len(notes) > 0 {
+		fmt.Fprintf(os.Stderr, "Successfully unpacked %d files to %s (%s)\n", written, opts.Dir, strings.Join(notes, ", "))
+	} else {
+		fmt.Fprintf(os.Stderr, "Successfully unpacked %d files to %s\n", written, opts.Dir)
+	}
+
+	return nil
+}
+
+const (
+	actionCreate    = "create"
+	actionOverwrite = "overwrite"
+	actionUnchanged = "unchanged"
+	actionConflict  = "conflict"
+	actionSkipped   = "skipped"
+)
+
+type unpackAction struct {
+	Name     string
+	Target   string
+	Status   string
+	Reason   string
+	Data     []byte
+	Existing []byte
+}
+
+type writtenFile struct {
+	action unpackAction
+	backup string
+}
+
+func writeAction(a unpackAction, opts UnpackOptions) (writtenFile, error) {
+	w := writtenFile{action: a}
+
+	if err := os.MkdirAll(filepath.Dir(a.Target), 0755); err != nil {
+		return w, fmt.Errorf("failed to create directory for %q: %w", a.Target, err)
+	}
+
+	if a.Status == actionOverwrite && opts.Backup {
+		backupPath := a.Target + ".bak"
+		if _, err := os.Stat(backupPath); err == nil {
+			timestamp := time.Now().Format("20060102T150405")
+			backupPath = fmt.Sprintf("%s.bak.%s", a.Target, timestamp)
+		}
+
+		if err := os.Rename(a.Target, backupPath); err != nil {
+			return w, fmt.Errorf("failed to backup %q: %w", a.Target, err)
+		}
+		w.backup = backupPath
+		fmt.Fprintf(os.Stderr, "Backed up: %s -> %s\n", a.Target, backupPath)
+	}
+
+	if err := os.WriteFile(a.Target, a.Data, 0644); err != nil {
+		if w.backup != "" {
+			os.Rename(w.backup, a.Target)
+		}
+		return w, fmt.Errorf("failed to write %q: %w", a.Target, err)
+	}
+	return w, nil
+}
+
+// missingDirs returns dir and those of its ancestors that do not exist yet,
+// deepest first.
+func missingDirs(dir string) []string {
+	var dirs []string
+	for {
+		if _, err := os.Stat(dir); err == nil || dir == filepath.Dir(dir) {
+			return dirs
+		}
+		dirs = append(dirs, dir)
+		dir = filepath.Dir(dir)
+	}
+}
+
+// rollback restores the files written by an unpack: created files are
+// removed, overwritten ones get their previous content back, and directories
+// created along the way are removed if empty.
+func rollback(done []writtenFile, createdDirs []string) {
+	for i := len(done) - 1; i >= 0; i-- {
+		w := done[i]
+		var err error
+		switch {
+		case w.action.Status == actionCreate:
+			err = os.Remove(w.action.Target)
+		case w.backup != "":
+			err = os.Rename(w.backup, w.action.Target)
+		default:
+			err = os.WriteFile(w.action.Target, w.action.Existing, 0644)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Rollback failed for %s: %v\n", w.action.Target, err)
 		}
+	}
+
+	// Deeper directories have longer paths; remove them first.
+	sort.Slice(createdDirs, func(i, j int) bool {
+		return len(createdDirs[i]) > len(createdDirs[j])
+	})
+	for _, dir := range createdDirs {
+		os.Remove(dir)
+	}
+
+	if len(done) > 0 {
+		fmt.Fprintf(os.Stderr, "Rolled back %d files\n", len(done))
+	}
+}
+
+// classifyEntry compares an entry with what is on disk at target. Identical
+// files are left alone so their modification times are not touched.
+func classifyEntry(target string, data []byte, opts UnpackOptions) (unpackAction, error) {
+	a := unpackAction{Target: target, Data: data}
+
+	info, err := os.Stat(target)
+	if err != nil {
+		if parent := fileParent(target); parent != "" {
+			a.Status, a.Reason = actionConflict, fmt.Sprintf("parent %s is not a directory", parent)
+			return a, nil
+		}
+		if !os.IsNotExist(err) {
+			return a, err
+		}
+		a.Status = actionCreate
+		return a, nil
+	}
+
+	if info.IsDir() {
+		a.Status, a.Reason = actionConflict, "is a directory"
+		return a, nil
+	}
+	if !info.Mode().IsRegular() {
+		a.Status, a.Reason = actionConflict, "exists and is not a regular file"
+		return a, nil
+	}
+
+	a.Existing, err = os.ReadFile(target)
+	if err != nil {
+		return a, fmt.Errorf("failed to read %q: %w", target, err)
+	}
+
+	switch {
+	case bytes.Equal(a.Existing, data):
+		a.Status = actionUnchanged
+	case opts.NoOverwrite:
+		a.Status, a.Reason = actionConflict, "file exists (use --backup to backup or remove --no-overwrite)"
+	default:
+		a.Status = actionOverwrite
+	}
+	return a, nil
+}
 
-		if _, err := os.Stat(targetPath); err == nil {
-			if opts.NoOverwrite {
-				return fmt.Errorf("file exists: %s (use --backup to backup or remove --no-overwrite)", targetPath)
+// fileParent returns the nearest existing ancestor of path if it is not a
+// directory, or "" otherwise.
+func fileParent(path string) string {
+	for dir := filepath.Dir(path); ; dir = filepath.Dir(dir) {
+		if info, err := os.Stat(dir); err == nil {
+			if info.IsDir() {
+				return ""
 			}
+			return dir
+		}
+		if dir == filepath.Dir(dir) {
+			return ""
+		}
+	}
+}
+
+func printAction(w io.Writer, a unpackAction, showDiff bool) {
+	switch a.Status {
+	case actionCreate:
+		fmt.Fprintf(w, "Would create: %s\n", a.Target)
+	case actionOverwrite:
+		fmt.Fprintf(w, "Would overwrite: %s\n", a.Target)
+		if showDiff {
+			fmt.Fprint(w, UnifiedDiff(a.Target, a.Target, a.Existing, a.Data))
+		}
+	case actionUnchanged:
+		fmt.Fprintf(w, "Unchanged: %s\n", a.Target)
+	case actionConflict:
+		fmt.Fprintf(w, "Conflict: %s (%s)\n", a.Target, a.Reason)
+	}
+}
+
+// selectEntries applies the include/exclude globs to the archive names, then
+// strips leading components and adds the prefix. Entries with no more than
+// StripComponents components are dropped, as tar does.
+func selectEntries(files []txtar.File, opts UnpackOptions) ([]txtar.File, error) {
+	if opts.StripComponents < 0 {
+		return nil, fmt.Errorf("--strip-components must not be negative")
+	}
+
+	filter := &Filter{
+		include: opts.Include,
+		exclude: opts.Exclude,
+	}
 
-			if opts.Backup {
-				backupPath := targetPath + ".bak"
-				if _, err := os.Stat(backupPath); err == nil {
-					timestamp := time.Now().Format("20060102T150405")
-					backupPath = fmt.Sprintf("%s.bak.%s", targetPath, timestamp)
-				}
-
-				if err := os.Rename(targetPath, backupPath); err != nil {
-					return fmt.Errorf("failed to backup %q: %w", targetPath, err)
-				}
-				fmt.Fprintf(os.Stderr, "Backed up: %s -> %s\n", targetPath, backupPath)
+	var selected []txtar.File
+	for _, file := range files {
+		if !filter.ShouldInclude(file.Name) {
+			continue
+		}
+
+		name := file.Name
+		if opts.StripComponents > 0 {
+			parts := strings.Split(name, "/")
+			if len(parts) <= opts.StripComponents {
+				continue
 			}
+			name = strings.Join(parts[opts.StripComponents:], "/")
 		}
 
-		if err := os.WriteFile(targetPath, file.Data, 0644); err != nil {
-			return fmt.Errorf("failed to write %q: %w", targetPath, err)
+		if opts.Prefix != "" {
+			name = path.Join(filepath.ToSlash(opts.Prefix), name)
 		}
+
+		selected = append(selected, txtar.File{Name: name, Data: file.Data})
 	}
 
-	return nil
+	if len(selected) == 0 && len(files) > 0 {
+		return nil, fmt.Errorf("no entries selected by --include, --exclude, or --strip-components")
+	}
+
+	return selected, nil
 }
 
+// validatePath rejects entry names that could point outside the output
+// directory on any platform. Backslashes count as separators so that
+// Windows-style traversal is caught everywhere.
 func validatePath(path string) error {
-	if filepath.IsAbs(path) {
+	if strings.ContainsRune(path, 0) {
+		return fmt.Errorf("NUL byte in path")
+	}
+
+	slashed := strings.ReplaceAll(filepath.ToSlash(path), `\`, "/")
+	if filepath.IsAbs(path) || strings.HasPrefix(slashed, "/") {
 		return fmt.Errorf("absolute paths not allowed")
 	}
+	if len(slashed) >= 2 && slashed[1] == ':' && isDriveLetter(slashed[0]) {
+		return fmt.Errorf("Windows drive paths not allowed")
+	}
 
-	cleaned := filepath.Clean(path)
-	if strings.HasPrefix(cleaned, "..") || strings.Contains(cleaned, "/../") {
-		return fmt.Errorf("path traversal detected")
+	for _, part := range strings.Split(slashed, "/") {
+		if part == ".." {
+			return fmt.Errorf("path traversal detected")
+		}
+	}
+
+	if cleaned := filepath.Clean(path); cleaned == "." {
+		return fmt.Errorf("path does not name a file")
 	}
 
 	return nil
 }
+
+func isDriveLetter(c byte) bool {
+	return ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z')
+}
@@ -3,7 +3,9 @@ package internal
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"golang.org/x/tools/txtar"
 )
@@ -106,3 +108,253 @@ func TestUnpackBackup(t *testing.T) {
 		t.Errorf("Expected 'new content', got %q", string(newContent))
 	}
 }
+
+func TestValidatePath(t *testing.T) {
+	bad := []string{
+		"/etc/passwd",
+		"../evil.txt",
+		"a/../../evil.txt",
+		`..\evil.txt`,
+		`C:\Windows\evil.txt`,
+		"c:evil.txt",
+		"//server/share/evil.txt",
+		"a/..",
+		"evil\x00.txt",
+	}
+	for _, name := range bad {
+		if err := validatePath(filepath.FromSlash(name)); err == nil {
+			t.Errorf("validatePath(%q) = nil, want error", name)
+		}
+	}
+
+	good := []string{"a.txt", "dir/b.txt", "..hidden", "a/..b/c"}
+	for _, name := range good {
+		if err := validatePath(filepath.FromSlash(name)); err != nil {
+			t.Errorf("validatePath(%q) = %v, want nil", name, err)
+		}
+	}
+}
+
+func TestUnpackSymlinkEscape(t *testing.T) {
+	tmpDir := t.TempDir()
+	outside := t.TempDir()
+
+	if err := os.Symlink(outside, filepath.Join(tmpDir, "link")); err != nil {
+		t.Skipf("symlinks not supported: %v", err)
+	}
+
+	archive := &txtar.Archive{
+		Files: []txtar.File{{Name: "link/passwd", Data: []byte("malicious")}},
+	}
+
+	if err := Unpack(archive, UnpackOptions{Dir: tmpDir}); err == nil {
+		t.Fatal("Expected error for write through symlink, got nil")
+	}
+	if _, err := os.Stat(filepath.Join(outside, "passwd")); err == nil {
+		t.Error("File was written outside the output directory")
+	}
+
+	if err := os.Mkdir(filepath.Join(tmpDir, "real"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("real", filepath.Join(tmpDir, "inside")); err != nil {
+		t.Fatal(err)
+	}
+
+	archive.Files = []txtar.File{{Name: "inside/ok.txt", Data: []byte("ok")}}
+	if err := Unpack(archive, UnpackOptions{Dir: tmpDir}); err != nil {
+		t.Fatalf("Symlink inside the output directory rejected: %v", err)
+	}
+}
+
+func TestUnpackDenyAndCaseFold(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	archive := &txtar.Archive{
+		Files: []txtar.File{
+			{Name: "ok.txt", Data: []byte("ok")},
+			{Name: ".GIT/hooks/pre-commit", Data: []byte("malicious")},
+		},
+	}
+
+	opts := UnpackOptions{Dir: tmpDir, Deny: DefaultDenyPatterns, CaseFold: "on"}
+	if err := Unpack(archive, opts); err == nil {
+		t.Fatal("Expected deny-list error, got nil")
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, "ok.txt")); err == nil {
+		t.Error("Files were written before the deny-list check failed")
+	}
+
+	archive.Files = []txtar.File{
+		{Name: "README", Data: []byte("a")},
+		{Name: "readme", Data: []byte("b")},
+	}
+	if err := Unpack(archive, opts); err == nil {
+		t.Fatal("Expected case collision error, got nil")
+	}
+
+	opts.CaseFold = "off"
+	if err := Unpack(archive, opts); err != nil {
+		t.Fatalf("Unpack with case folding off failed: %v", err)
+	}
+
+	archive.Files = []txtar.File{{Name: "sub/.git/hooks/pre-commit", Data: []byte("malicious")}}
+	if err := Unpack(archive, opts); err == nil {
+		t.Error("Expected deny-list error for a nested .git, got nil")
+	}
+}
+
+func TestCaseFoldingProbeWritesNothing(t *testing.T) {
+	tmpDir := t.TempDir()
+	os.WriteFile(filepath.Join(tmpDir, "existing.txt"), []byte("x"), 0644)
+
+	archive := &txtar.Archive{Files: []txtar.File{{Name: "new.txt", Data: []byte("n")}}}
+	if err := Unpack(archive, UnpackOptions{Dir: tmpDir, DryRun: true, CaseFold: "auto"}); err != nil {
+		t.Fatalf("Dry run failed: %v", err)
+	}
+
+	entries, _ := os.ReadDir(tmpDir)
+	if len(entries) != 1 || entries[0].Name() != "existing.txt" {
+		t.Errorf("Dry run changed the directory: %v", entries)
+	}
+
+	// Probing sees the same file under a flipped-case name only on
+	// case-insensitive file systems.
+	fold, err := caseFolding(tmpDir, "auto")
+	if err != nil {
+		t.Fatalf("caseFolding failed: %v", err)
+	}
+	_, statErr := os.Stat(filepath.Join(tmpDir, "EXISTING.TXT"))
+	if fold != (statErr == nil) {
+		t.Errorf("caseFolding = %v, but Stat of flipped name returned %v", fold, statErr)
+	}
+}
+
+func TestUnpackSelectEntries(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	archive := &txtar.Archive{
+		Files: []txtar.File{
+			{Name: "repo/go.mod", Data: []byte("module x\n")},
+			{Name: "repo/internal/a.go", Data: []byte("package internal\n")},
+			{Name: "repo/internal/a_test.go", Data: []byte("package internal\n")},
+			{Name: "repo/cmd/main.go", Data: []byte("package main\n")},
+			{Name: "top.txt", Data: []byte("dropped by strip\n")},
+		},
+	}
+
+	opts := UnpackOptions{
+		Dir:             tmpDir,
+		Include:         []string{"repo/internal/**", "top.txt"},
+		Exclude:         []string{"**/*_test.go"},
+		StripComponents: 1,
+		Prefix:          "vendor/",
+	}
+
+	if err := Unpack(archive, opts); err != nil {
+		t.Fatalf("Unpack failed: %v", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(tmpDir, "vendor", "internal", "a.go")); err != nil {
+		t.Errorf("Expected vendor/internal/a.go: %v", err)
+	}
+	for _, name := range []string{"vendor/internal/a_test.go", "vendor/cmd/main.go", "vendor/go.mod", "top.txt", "vendor/top.txt"} {
+		if _, err := os.Stat(filepath.Join(tmpDir, filepath.FromSlash(name))); err == nil {
+			t.Errorf("Did not expect %s to be written", name)
+		}
+	}
+
+	opts.Include = []string{"missing/**"}
+	if err := Unpack(archive, opts); err == nil {
+		t.Error("Expected error when no entries are selected")
+	}
+}
+
+func TestUnpackSkipsIdenticalFiles(t *testing.T) {
+	tmpDir := t.TempDir()
+	same := filepath.Join(tmpDir, "same.txt")
+	if err := os.WriteFile(same, []byte("same\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-time.Hour).Truncate(time.Second)
+	if err := os.Chtimes(same, old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	archive := &txtar.Archive{
+		Files: []txtar.File{
+			{Name: "same.txt", Data: []byte("same\n")},
+			{Name: "new.txt", Data: []byte("new\n")},
+		},
+	}
+
+	if err := Unpack(archive, UnpackOptions{Dir: tmpDir, NoOverwrite: true}); err != nil {
+		t.Fatalf("Unpack failed: %v", err)
+	}
+
+	info, err := os.Stat(same)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !info.ModTime().Equal(old) {
+		t.Errorf("Identical file was rewritten: mtime %v, want %v", info.ModTime(), old)
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, "new.txt")); err != nil {
+		t.Errorf("New file not written: %v", err)
+	}
+}
+
+func TestClassifyEntry(t *testing.T) {
+	tmpDir := t.TempDir()
+	os.WriteFile(filepath.Join(tmpDir, "file.txt"), []byte("old\n"), 0644)
+	os.Mkdir(filepath.Join(tmpDir, "dir"), 0755)
+
+	tests := []struct {
+		name string
+		opts UnpackOptions
+		want string
+	}{
+		{"missing.txt", UnpackOptions{}, actionCreate},
+		{"file.txt", UnpackOptions{}, actionOverwrite},
+		{"file.txt", UnpackOptions{NoOverwrite: true}, actionConflict},
+		{"dir", UnpackOptions{}, actionConflict},
+		{"file.txt/child", UnpackOptions{}, actionConflict},
+	}
+
+	for _, tt := range tests {
+		a, err := classifyEntry(filepath.Join(tmpDir, tt.name), []byte("new\n"), tt.opts)
+		if err != nil {
+			t.Fatalf("classifyEntry(%s) failed: %v", tt.name, err)
+		}
+		if a.Status != tt.want {
+			t.Errorf("classifyEntry(%s) = %s, want %s", tt.name, a.Status, tt.want)
+		}
+	}
+
+	a, _ := classifyEntry(filepath.Join(tmpDir, "file.txt"), []byte("old\n"), UnpackOptions{})
+	if a.Status != actionUnchanged {
+		t.Errorf("Expected identical file to be unchanged, got %s", a.Status)
+	}
+}
+
+func TestUnifiedDiff(t *testing.T) {
+	old := "a\nb\nc\nd\ne\nf\ng\nh\ni\nj\n"
+	new := "a\nb\nC\nd\ne\nf\ng\nh\ni\nj"
+
+	want := "--- x\n+++ x\n" +
+		"@@ -1,10 +1,10 @@\n a\n b\n-c\n+C\n d\n e\n f\n g\n h\n i\n-j\n+j\n\\ No newline at end of file\n"
+	if got := UnifiedDiff("x", "x", []byte(old), []byte(new)); got != want {
+		t.Errorf("UnifiedDiff mismatch:\ngot:\n%s\nwant:\n%s", got, want)
+	}
+
+	// Seven unchanged lines between changes split the hunk.
+	old = "a\nb\nc\nd\ne\nf\ng\nh\ni\nj\nk\n"
+	new = "A\nb\nc\nd\ne\nf\ng\nh\ni\nj\nK\n"
+	if got := UnifiedDiff("x", "x", []byte(old), []byte(new)); strings.Count(got, "@@ -") != 2 {
+		t.Errorf("Expected two hunks, got:\n%s", got)
+	}
+
+	if got := UnifiedDiff("x", "x", []byte(old), []byte(old)); got != "" {
+		t.Errorf("Expected empty diff for equal input, got %q", got)
+	}
+}
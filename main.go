@@ -6,8 +6,11 @@ import (
 	"github.com/phlv/txtar/cmd"
 )
 
+var version = "dev"
+
 func main() {
+	cmd.SetVersion(version)
 	if err := cmd.Execute(); err != nil {
-		os.Exit(1)
+		os.Exit(cmd.ExitCode(err))
 	}
 }